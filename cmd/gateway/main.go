@@ -1,17 +1,35 @@
 package main
 
 import (
+	"context"
+	"ecomGateway/internal/cart"
 	"ecomGateway/internal/config"
+	"ecomGateway/internal/grpc/interceptors"
 	ordergrpc "ecomGateway/internal/grpc/order"
 	productgrpc "ecomGateway/internal/grpc/product"
 	usergrpc "ecomGateway/internal/grpc/user"
+	"ecomGateway/internal/health"
 	httphandler "ecomGateway/internal/http_handler"
+	"ecomGateway/internal/idempotency"
+	jwtmethod "ecomGateway/internal/lib/jwt_method"
+	requestid "ecomGateway/internal/lib/request_id"
+	tlsconfig "ecomGateway/internal/lib/tls_config"
+	"ecomGateway/internal/lockout"
+	"ecomGateway/internal/metrics"
 	"ecomGateway/internal/processor"
+	"ecomGateway/internal/ratelimit"
+	"ecomGateway/internal/tracing"
+	"errors"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -20,6 +38,10 @@ const (
 	envProd  = "prod"
 )
 
+// tracerShutdownTimeout bounds flushing the buffered spans on shutdown. The
+// drain gets its own deadline so a slow drain cannot leave none for the flush.
+const tracerShutdownTimeout = 5 * time.Second
+
 func main() {
 	cfg := config.MustLoad()
 
@@ -28,51 +50,261 @@ func main() {
 	log.Info("starting url-shortener")
 	log.Debug("debug messages are enabled")
 
-	userClient, err := usergrpc.New(log, cfg.UserTarget, cfg.UserTimeout, cfg.UserRetries)
+	tracer, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName: "ecom-gateway",
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("failed to init tracing", "err", err)
+		os.Exit(1)
+	}
+
+	userCreds, err := transportCredentials(log, cfg.User.TLS)
+	if err != nil {
+		log.Error("failed to load user TLS credentials", "err", err)
+		os.Exit(1)
+	}
+
+	userClient, err := usergrpc.New(log, cfg.User.Target, cfg.User.Timeout, retryPolicy(cfg.Retry, cfg.User.Retries), breakerPolicy(cfg.Breaker), userCreds...)
 
 	if err != nil {
 		log.Error("failed to init user client", "err", err)
 		os.Exit(1)
 	}
 
-	orderClient, err := ordergrpc.New(log, cfg.OrderTarget, cfg.OrderTimeout, cfg.UserRetries)
+	orderCreds, err := transportCredentials(log, cfg.Order.TLS)
+	if err != nil {
+		log.Error("failed to load order TLS credentials", "err", err)
+		os.Exit(1)
+	}
+
+	orderClient, err := ordergrpc.New(log, cfg.Order.Target, cfg.Order.Timeout, retryPolicy(cfg.Retry, cfg.Order.Retries), breakerPolicy(cfg.Breaker), orderCreds...)
 
 	if err != nil {
 		log.Error("failed to init order client", "err", err)
 		os.Exit(1)
 	}
 
-	productClient, err := productgrpc.New(log, cfg.ProductTarget, cfg.ProductTimeout, cfg.ProductRetries)
+	productCreds, err := transportCredentials(log, cfg.Product.TLS)
+	if err != nil {
+		log.Error("failed to load product TLS credentials", "err", err)
+		os.Exit(1)
+	}
+
+	productClient, err := productgrpc.New(log, cfg.Product.Target, cfg.Product.Timeout, retryPolicy(cfg.Retry, cfg.Product.Retries), breakerPolicy(cfg.Breaker), productCreds...)
 
 	if err != nil {
 		log.Error("failed to init product client", "err", err)
 		os.Exit(1)
 	}
 
-	processor := processor.NewProcessorService(*userClient, *orderClient, *productClient)
+	publicKey, err := jwtmethod.LoadPublicKey(cfg.JWT.PublicKeyPath, cfg.JWT.PublicKeyPEM)
+	if err != nil {
+		log.Error("failed to load jwt public key", "err", err)
+		os.Exit(1)
+	}
 
-	httphandler := httphandler.NewHTTPHandler(processor, log)
+	clientIP, err := ratelimit.NewIPResolver(cfg.RateLimit.TrustedProxies)
+	if err != nil {
+		log.Error("failed to parse trusted proxies", "err", err)
+		os.Exit(1)
+	}
 
-	router := chi.NewRouter()
+	processor := processor.NewProcessorService(*userClient, *orderClient, *productClient, log, processor.Options{
+		ProductCache: processor.CacheOptions{Size: cfg.ProductCache.Size, TTL: cfg.ProductCache.TTL},
+		Carts:        cart.NewMemoryStore(cfg.Cart.TTL, cfg.Cart.MaxItems),
+	})
 
-	httphandler.RegisterRoutes(router)
+	var idempotencyStore idempotency.Store
+	if cfg.Idempotency.TTL > 0 {
+		idempotencyStore = idempotency.NewMemoryStore(cfg.Idempotency.TTL)
+	}
+
+	handler := httphandler.NewHTTPHandler(processor, log, httphandler.Options{
+		PublicKey:    publicKey,
+		AdminUserIDs: cfg.AdminUserIDs,
+		ClientIP:     clientIP,
+		RateLimit: httphandler.RateLimitOptions{
+			Store: ratelimit.NewMemoryStore(),
+			IP:    ratelimit.Limit{Rate: cfg.RateLimit.RPS, Burst: cfg.RateLimit.Burst},
+			Auth:  ratelimit.Limit{Rate: cfg.RateLimit.AuthRPS, Burst: cfg.RateLimit.AuthBurst},
+			User:  ratelimit.Limit{Rate: cfg.RateLimit.UserRPS, Burst: cfg.RateLimit.UserBurst},
+		},
+		Lockout: httphandler.LockoutOptions{
+			Login: lockoutPolicy(cfg.Lockout, cfg.Lockout.LoginThreshold),
+			IP:    lockoutPolicy(cfg.Lockout, cfg.Lockout.IPThreshold),
+		},
+		Idempotency: idempotencyStore,
+		CacheControl: httphandler.CacheControlOptions{
+			Products: cfg.CacheControl.Products,
+			Product:  cfg.CacheControl.Product,
+			Orders:   cfg.CacheControl.Orders,
+			Order:    cfg.CacheControl.Order,
+		},
+	})
+
+	checker := health.NewChecker(log, cfg.Readiness.Timeout, cfg.Readiness.HealthRPC,
+		health.Dependency{Name: "user", Conn: userClient.Conn(), Breaker: userClient.Breaker(), Required: !slices.Contains(cfg.Readiness.Optional, "user")},
+		health.Dependency{Name: "order", Conn: orderClient.Conn(), Breaker: orderClient.Breaker(), Required: !slices.Contains(cfg.Readiness.Optional, "order")},
+		health.Dependency{Name: "product", Conn: productClient.Conn(), Breaker: productClient.Breaker(), Required: !slices.Contains(cfg.Readiness.Optional, "product")},
+	)
+
+	router := chi.NewRouter()
+	router.Use(requestid.Middleware)
+	router.Use(tracing.Middleware(router))
+	router.Use(metrics.Middleware(router))
 
-	log.Info("starting server", slog.String("address", cfg.HttpAddress))
+	router.Handle("/metrics", metrics.Handler())
+	router.Get("/healthz", checker.Liveness)
+	router.Get("/readyz", checker.Readiness)
+	handler.RegisterRoutes(router)
 
 	srv := &http.Server{
-		Addr:         cfg.HttpAddress,
+		Addr:         cfg.HTTP.Address,
 		Handler:      router,
-		ReadTimeout:  cfg.HttpTimeout,
-		WriteTimeout: cfg.HttpTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
+		ReadTimeout:  cfg.HTTP.Timeout,
+		WriteTimeout: cfg.HTTP.Timeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}
+	servers := []*http.Server{srv}
+
+	if cfg.HTTP.TLS.Enabled {
+		srv.TLSConfig, err = tlsconfig.NewServerConfig(log, tlsconfig.ServerOptions{
+			CertFile:     cfg.HTTP.TLS.CertFile,
+			KeyFile:      cfg.HTTP.TLS.KeyFile,
+			MinVersion:   cfg.HTTP.TLS.MinVersion,
+			CipherSuites: cfg.HTTP.TLS.CipherSuites,
+		})
+		if err != nil {
+			log.Error("failed to load server TLS certificate", "err", err)
+			os.Exit(1)
+		}
+
+		if cfg.HTTP.TLS.RedirectAddress != "" {
+			servers = append(servers, &http.Server{
+				Addr:         cfg.HTTP.TLS.RedirectAddress,
+				Handler:      httphandler.RedirectToHTTPS(cfg.HTTP.Address),
+				ReadTimeout:  cfg.HTTP.Timeout,
+				WriteTimeout: cfg.HTTP.Timeout,
+				IdleTimeout:  cfg.HTTP.IdleTimeout,
+			})
+		}
 	}
 
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serverErr := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			log.Info("starting server", slog.String("address", s.Addr), slog.Bool("tls", s.TLSConfig != nil))
+
+			var err error
+			if s.TLSConfig != nil {
+				err = s.ListenAndServeTLS("", "")
+			} else {
+				err = s.ListenAndServe()
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-serverErr:
 		log.Error("failed to start server", slog.String("error", err.Error()))
+		closeClients(log, userClient, orderClient, productClient)
 		os.Exit(1)
+	case <-ctx.Done():
+		log.Info("shutting down server", slog.Duration("timeout", cfg.HTTP.ShutdownTimeout))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			log.Error("failed to drain in-flight requests", slog.String("address", s.Addr), slog.String("error", err.Error()))
+			s.Close()
+		}
 	}
 	log.Info("server stopped")
 
+	closeClients(log, userClient, orderClient, productClient)
+	log.Info("backend connections closed")
+
+	tracerCtx, cancelTracer := context.WithTimeout(context.Background(), tracerShutdownTimeout)
+	defer cancelTracer()
+
+	if err := tracer.Shutdown(tracerCtx); err != nil {
+		log.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
+}
+
+type closer interface {
+	Close() error
+}
+
+func closeClients(log *slog.Logger, clients ...closer) {
+	for _, c := range clients {
+		if err := c.Close(); err != nil {
+			log.Error("failed to close gRPC client", slog.String("error", err.Error()))
+		}
+	}
+}
+
+// transportCredentials returns the dial options that replace the clients'
+// plaintext default when TLS is enabled for a backend.
+func transportCredentials(log *slog.Logger, cfg config.TLSConfig) ([]grpc.DialOption, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	creds, err := tlsconfig.NewClientCredentials(log, tlsconfig.ClientOptions{
+		CAFile:     cfg.CAFile,
+		CertFile:   cfg.CertFile,
+		KeyFile:    cfg.KeyFile,
+		ServerName: cfg.ServerName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []grpc.DialOption{grpc.WithTransportCredentials(creds)}, nil
+}
+
+// retryPolicy turns the retries setting of a backend, which counts every
+// attempt of a call including the first, into the retries after the first.
+func retryPolicy(cfg config.RetryConfig, retries int) interceptors.RetryPolicy {
+	return interceptors.RetryPolicy{
+		MaxRetries: uint(max(retries-1, 0)),
+		Backoff:    cfg.Backoff,
+		MaxBackoff: cfg.MaxBackoff,
+		Jitter:     cfg.Jitter,
+	}
+}
+
+func breakerPolicy(cfg config.BreakerConfig) interceptors.BreakerPolicy {
+	return interceptors.BreakerPolicy{
+		FailureRatio:     cfg.FailureRatio,
+		MinRequests:      uint(cfg.MinRequests),
+		Window:           cfg.Window,
+		Cooldown:         cfg.Cooldown,
+		HalfOpenRequests: uint(cfg.HalfOpenRequests),
+	}
+}
+
+func lockoutPolicy(cfg config.LockoutConfig, threshold int) lockout.Policy {
+	return lockout.Policy{
+		Threshold: threshold,
+		Duration:  cfg.Duration,
+		BaseDelay: cfg.BaseDelay,
+		MaxDelay:  cfg.MaxDelay,
+	}
 }
 
 func setupLogger(env string) *slog.Logger {
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Stats counts cache lookups since the cache was created.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Entries   int
+}
+
+type entry[K comparable, V any] struct {
+	key     K
+	value   V
+	expires time.Time
+}
+
+// Cache is an LRU cache whose entries also expire after a TTL. Concurrent
+// misses for the same key share a single load. Cached values are shared
+// between callers and must not be modified.
+type Cache[K comparable, V any] struct {
+	name string
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	group singleflight.Group
+
+	mu         sync.Mutex
+	order      *list.List
+	items      map[K]*list.Element
+	generation uint64
+	stats      Stats
+}
+
+// New creates a cache holding at most size entries for ttl each.
+func New[K comparable, V any](name string, size int, ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		name:  name,
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		order: list.New(),
+		items: make(map[K]*list.Element, size),
+	}
+}
+
+func (c *Cache[K, V]) Name() string {
+	return c.name
+}
+
+// Get returns the cached value for key or calls load to fill it. load runs
+// detached from the cancellation of ctx because other callers may be waiting
+// for its result; a caller whose ctx ends stops waiting. Errors are not
+// cached.
+func (c *Cache[K, V]) Get(ctx context.Context, key K, load func(context.Context) (V, error)) (V, error) {
+	value, generation, ok := c.lookup(key)
+	if ok {
+		return value, nil
+	}
+
+	// Loads started before an invalidation must neither be joined nor
+	// stored, hence the generation in the flight key.
+	flightKey := fmt.Sprintf("%d/%v", generation, key)
+	loadCtx := context.WithoutCancel(ctx)
+	ch := c.group.DoChan(flightKey, func() (any, error) {
+		value, err := load(loadCtx)
+		if err != nil {
+			return value, err
+		}
+		c.store(key, value, generation)
+		return value, nil
+	})
+
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			var zero V
+			return zero, res.Err
+		}
+		return res.Val.(V), nil
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (c *Cache[K, V]) lookup(key K) (V, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		if c.now().Before(e.expires) {
+			c.order.MoveToFront(el)
+			c.stats.Hits++
+			return e.value, c.generation, true
+		}
+		c.remove(el)
+	}
+
+	c.stats.Misses++
+	var zero V
+	return zero, c.generation, false
+}
+
+func (c *Cache[K, V]) store(key K, value V, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry[K, V])
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// Delete drops key; loads already in flight will not store their result.
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Purge drops every entry; loads already in flight will not store their
+// result.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.order.Init()
+	clear(c.items)
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+func (c *Cache[K, V]) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry[K, V]).key)
+}
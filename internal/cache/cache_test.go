@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCache(size int, ttl time.Duration) (*Cache[int, string], *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[int, string]("test", size, ttl)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func loader(calls *atomic.Int64, value string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		calls.Add(1)
+		return value, nil
+	}
+}
+
+func TestCache_HitMissAndExpiry(t *testing.T) {
+	ctx := context.Background()
+	c, now := newTestCache(10, time.Minute)
+	var calls atomic.Int64
+
+	v, err := c.Get(ctx, 1, loader(&calls, "a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", v)
+
+	v, err = c.Get(ctx, 1, loader(&calls, "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", v, "served from the cache")
+	assert.EqualValues(t, 1, calls.Load())
+
+	*now = now.Add(time.Minute)
+	v, err = c.Get(ctx, 1, loader(&calls, "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", v, "expired entries are reloaded")
+
+	assert.Equal(t, Stats{Hits: 1, Misses: 2, Entries: 1}, c.Stats())
+}
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(2, time.Minute)
+	var calls atomic.Int64
+
+	c.Get(ctx, 1, loader(&calls, "1"))
+	c.Get(ctx, 2, loader(&calls, "2"))
+	c.Get(ctx, 1, loader(&calls, "1"))
+	c.Get(ctx, 3, loader(&calls, "3"))
+	require.EqualValues(t, 3, calls.Load())
+
+	c.Get(ctx, 1, loader(&calls, "1"))
+	assert.EqualValues(t, 3, calls.Load(), "recently used entries are kept")
+
+	c.Get(ctx, 2, loader(&calls, "2"))
+	assert.EqualValues(t, 4, calls.Load(), "the least recently used entry was evicted")
+	assert.EqualValues(t, 2, c.Stats().Evictions)
+}
+
+func TestCache_CoalescesConcurrentMisses(t *testing.T) {
+	c, _ := newTestCache(10, time.Minute)
+	var calls atomic.Int64
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(context.Background(), 1, func(context.Context) (string, error) {
+				calls.Add(1)
+				<-release
+				return "a", nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, "a", v)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return c.Stats().Misses == 10 }, time.Second, time.Millisecond)
+	// Let the last callers join the flight after counting their miss.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, calls.Load(), "one backend call for all waiters")
+}
+
+func TestCache_ErrorsAreNotCached(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(10, time.Minute)
+	var calls atomic.Int64
+
+	_, err := c.Get(ctx, 1, func(context.Context) (string, error) {
+		calls.Add(1)
+		return "", errors.New("backend down")
+	})
+	require.Error(t, err)
+
+	v, err := c.Get(ctx, 1, loader(&calls, "a"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", v)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestCache_InvalidationDuringLoad(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCache(10, time.Minute)
+	var calls atomic.Int64
+
+	v, err := c.Get(ctx, 1, func(context.Context) (string, error) {
+		c.Delete(1)
+		return "stale", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "stale", v, "the caller still gets its result")
+
+	v, err = c.Get(ctx, 1, loader(&calls, "fresh"))
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", v, "a load started before Delete is not stored")
+
+	c.Purge()
+	v, err = c.Get(ctx, 1, loader(&calls, "after purge"))
+	require.NoError(t, err)
+	assert.Equal(t, "after purge", v)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestCache_CallerCancellation(t *testing.T) {
+	c, _ := newTestCache(10, time.Minute)
+	release := make(chan struct{})
+	loaded := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	_, err := c.Get(ctx, 1, func(loadCtx context.Context) (string, error) {
+		defer close(loaded)
+		<-release
+		return "a", loadCtx.Err()
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	<-loaded
+
+	var calls atomic.Int64
+	require.Eventually(t, func() bool { return c.Stats().Entries == 1 }, time.Second, time.Millisecond)
+	v, err := c.Get(context.Background(), 1, loader(&calls, "b"))
+	require.NoError(t, err)
+	assert.Equal(t, "a", v, "the load finished for the cache even though its caller left")
+}
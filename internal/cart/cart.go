@@ -0,0 +1,285 @@
+package cart
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	ttlmap "ecomGateway/internal/lib/ttl_map"
+)
+
+var (
+	ErrItemNotFound     = errors.New("product is not in the cart")
+	ErrFull             = errors.New("cart is full")
+	ErrQuantityOverflow = errors.New("cart quantity overflows")
+	ErrCheckoutClaimed  = errors.New("cart checkout is already in progress")
+)
+
+// Item is one line of a cart. Prices are not stored: they are looked up
+// whenever the cart is shown or checked out.
+type Item struct {
+	ProductID int64
+	Quantity  int32
+}
+
+// Checkout is a claimed cart. Items is the cart as it was when the checkout
+// began, and ID names the attempt to place it: both stay the same until the
+// checkout is completed or released, so that a checkout whose outcome was
+// lost is resumed as the same order rather than placed again. Resumed is set
+// when an earlier claim on the checkout expired before it was ended.
+type Checkout struct {
+	ID      string
+	Items   []Item
+	Resumed bool
+}
+
+// Store keeps the carts of users and their checkout claims. A user's
+// requests may reach any gateway instance, so with MemoryStore each replica
+// would keep a cart and a claim of its own; they need a store they all share.
+type Store interface {
+	// Items returns the lines of the cart of userID in the order they were
+	// first added; an empty cart has no lines.
+	Items(ctx context.Context, userID int64) ([]Item, error)
+	// Add puts quantity of productID into the cart, on top of what is already
+	// there.
+	Add(ctx context.Context, userID, productID int64, quantity int32) error
+	// Set replaces the quantity of a product that is already in the cart.
+	Set(ctx context.Context, userID, productID int64, quantity int32) error
+	// Remove drops the line of productID.
+	Remove(ctx context.Context, userID, productID int64) error
+	// Clear empties the cart.
+	Clear(ctx context.Context, userID int64) error
+
+	// BeginCheckout claims the cart of userID for a checkout, failing with
+	// ErrCheckoutClaimed while another claim is held. The cart can still be
+	// changed; the claim only keeps a second checkout out. A checkout that was
+	// neither completed nor released is claimed again once its claim expires
+	// and returned unchanged, so the caller finishes it instead of starting a
+	// new one.
+	BeginCheckout(ctx context.Context, userID int64) (*Checkout, error)
+	// CompleteCheckout takes the items of the checkout out of the cart and
+	// ends it. Only the ordered quantities are subtracted, so whatever was
+	// added during the checkout stays in the cart.
+	CompleteCheckout(ctx context.Context, userID int64) error
+	// ReleaseCheckout ends the checkout and leaves the cart as it is.
+	ReleaseCheckout(ctx context.Context, userID int64) error
+}
+
+const (
+	// sweepInterval is how often MemoryStore drops expired carts.
+	sweepInterval = time.Minute
+	// checkoutClaimTTL is how long a checkout keeps its claim. It outlasts
+	// any request, so only a checkout left unfinished can be resumed.
+	checkoutClaimTTL = time.Minute
+)
+
+type memoryCart struct {
+	items   []Item
+	expires time.Time
+}
+
+type memoryCheckout struct {
+	Checkout
+	claimedUntil time.Time
+}
+
+// MemoryStore keeps a cart for ttl after its last change and up to maxItems
+// lines in it. A zero ttl keeps carts forever and a zero maxItems does not
+// limit them.
+type MemoryStore struct {
+	ttl      time.Duration
+	maxItems int
+
+	mu        sync.Mutex
+	carts     *ttlmap.Map[int64, *memoryCart]
+	checkouts map[int64]*memoryCheckout
+	now       func() time.Time
+}
+
+func NewMemoryStore(ttl time.Duration, maxItems int) *MemoryStore {
+	return &MemoryStore{
+		ttl:      ttl,
+		maxItems: maxItems,
+		carts: ttlmap.New[int64](sweepInterval, func(c *memoryCart, now time.Time) bool {
+			return ttl > 0 && !now.Before(c.expires)
+		}),
+		checkouts: make(map[int64]*memoryCheckout),
+		now:       time.Now,
+	}
+}
+
+func (s *MemoryStore) Items(_ context.Context, userID int64) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cart(userID)
+	if c == nil {
+		return nil, nil
+	}
+	return slices.Clone(c.items), nil
+}
+
+func (s *MemoryStore) Add(_ context.Context, userID, productID int64, quantity int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cart(userID)
+	if c == nil {
+		c = &memoryCart{}
+		s.carts.Set(userID, c)
+	}
+
+	if i := c.index(productID); i >= 0 {
+		if int64(c.items[i].Quantity)+int64(quantity) > math.MaxInt32 {
+			return ErrQuantityOverflow
+		}
+		c.items[i].Quantity += quantity
+	} else {
+		if s.maxItems > 0 && len(c.items) >= s.maxItems {
+			return ErrFull
+		}
+		c.items = append(c.items, Item{ProductID: productID, Quantity: quantity})
+	}
+
+	s.touch(c)
+	return nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, userID, productID int64, quantity int32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, i := s.line(userID, productID)
+	if i < 0 {
+		return ErrItemNotFound
+	}
+
+	c.items[i].Quantity = quantity
+	s.touch(c)
+	return nil
+}
+
+func (s *MemoryStore) Remove(_ context.Context, userID, productID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, i := s.line(userID, productID)
+	if i < 0 {
+		return ErrItemNotFound
+	}
+
+	c.items = slices.Delete(c.items, i, i+1)
+	if len(c.items) == 0 {
+		s.carts.Delete(userID)
+		return nil
+	}
+	s.touch(c)
+	return nil
+}
+
+func (s *MemoryStore) Clear(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.carts.Delete(userID)
+	return nil
+}
+
+func (s *MemoryStore) BeginCheckout(_ context.Context, userID int64) (*Checkout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	co, ok := s.checkouts[userID]
+	if ok && now.Before(co.claimedUntil) {
+		return nil, ErrCheckoutClaimed
+	}
+	resumed := ok
+	if !ok {
+		co = &memoryCheckout{Checkout: Checkout{ID: newCheckoutID()}}
+		if c := s.cart(userID); c != nil {
+			co.Items = slices.Clone(c.items)
+		}
+		s.checkouts[userID] = co
+	}
+	co.claimedUntil = now.Add(checkoutClaimTTL)
+
+	return &Checkout{ID: co.ID, Items: slices.Clone(co.Items), Resumed: resumed}, nil
+}
+
+func (s *MemoryStore) CompleteCheckout(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	co, ok := s.checkouts[userID]
+	if !ok {
+		return nil
+	}
+	delete(s.checkouts, userID)
+
+	c := s.cart(userID)
+	if c == nil {
+		return nil
+	}
+
+	for _, item := range co.Items {
+		i := c.index(item.ProductID)
+		if i < 0 {
+			continue
+		}
+		if c.items[i].Quantity > item.Quantity {
+			c.items[i].Quantity -= item.Quantity
+		} else {
+			c.items = slices.Delete(c.items, i, i+1)
+		}
+	}
+
+	if len(c.items) == 0 {
+		s.carts.Delete(userID)
+		return nil
+	}
+	s.touch(c)
+	return nil
+}
+
+func (s *MemoryStore) ReleaseCheckout(_ context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.checkouts, userID)
+	return nil
+}
+
+// cart returns the live cart of userID, if any.
+func (s *MemoryStore) cart(userID int64) *memoryCart {
+	c, _ := s.carts.Get(userID, s.now())
+	return c
+}
+
+// line finds the cart of userID and the index of productID in it, or -1.
+func (s *MemoryStore) line(userID, productID int64) (*memoryCart, int) {
+	c := s.cart(userID)
+	if c == nil {
+		return nil, -1
+	}
+	return c, c.index(productID)
+}
+
+func (s *MemoryStore) touch(c *memoryCart) {
+	c.expires = s.now().Add(s.ttl)
+}
+
+func newCheckoutID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (c *memoryCart) index(productID int64) int {
+	return slices.IndexFunc(c.items, func(item Item) bool { return item.ProductID == productID })
+}
@@ -0,0 +1,165 @@
+package cart
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(ttl time.Duration, maxItems int) (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(ttl, maxItems)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore_Lines(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour, 0)
+
+	require.NoError(t, store.Add(ctx, 1, 10, 1))
+	require.NoError(t, store.Add(ctx, 1, 20, 2))
+	require.NoError(t, store.Add(ctx, 1, 10, 3))
+
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: 4}, {ProductID: 20, Quantity: 2}}, items, "repeated adds fold into the first line")
+
+	require.NoError(t, store.Set(ctx, 1, 20, 7))
+	require.NoError(t, store.Remove(ctx, 1, 10))
+	items, err = store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 20, Quantity: 7}}, items)
+
+	assert.ErrorIs(t, store.Set(ctx, 1, 10, 1), ErrItemNotFound)
+	assert.ErrorIs(t, store.Remove(ctx, 1, 10), ErrItemNotFound)
+	assert.ErrorIs(t, store.Remove(ctx, 2, 20), ErrItemNotFound, "carts are per user")
+
+	require.NoError(t, store.Clear(ctx, 1))
+	items, err = store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func TestMemoryStore_ReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour, 0)
+	require.NoError(t, store.Add(ctx, 1, 10, 1))
+
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	items[0].Quantity = 99
+
+	items, err = store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, items[0].Quantity)
+}
+
+func TestMemoryStore_Limits(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour, 2)
+
+	require.NoError(t, store.Add(ctx, 1, 10, math.MaxInt32-1))
+	require.NoError(t, store.Add(ctx, 1, 20, 1))
+	assert.ErrorIs(t, store.Add(ctx, 1, 30, 1), ErrFull)
+	assert.NoError(t, store.Add(ctx, 1, 20, 1), "a full cart still takes more of its products")
+
+	assert.NoError(t, store.Add(ctx, 1, 10, 1))
+	assert.ErrorIs(t, store.Add(ctx, 1, 10, 1), ErrQuantityOverflow)
+
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: math.MaxInt32}, {ProductID: 20, Quantity: 2}}, items)
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore(time.Hour, 0)
+
+	require.NoError(t, store.Add(ctx, 1, 10, 1))
+	require.NoError(t, store.Add(ctx, 2, 10, 1))
+
+	*now = now.Add(30 * time.Minute)
+	require.NoError(t, store.Set(ctx, 1, 10, 2))
+
+	*now = now.Add(45 * time.Minute)
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: 2}}, items, "changes extend the cart's life")
+
+	items, err = store.Items(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, items, "an untouched cart expires")
+
+	store.mu.Lock()
+	assert.Equal(t, 1, store.carts.Len(), "expired carts are swept")
+	store.mu.Unlock()
+}
+
+func TestMemoryStore_Checkout(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour, 0)
+
+	require.NoError(t, store.Add(ctx, 1, 10, 2))
+	require.NoError(t, store.Add(ctx, 1, 20, 1))
+
+	checkout, err := store.BeginCheckout(ctx, 1)
+	require.NoError(t, err)
+	assert.NotEmpty(t, checkout.ID)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: 2}, {ProductID: 20, Quantity: 1}}, checkout.Items)
+	assert.False(t, checkout.Resumed)
+
+	_, err = store.BeginCheckout(ctx, 1)
+	assert.ErrorIs(t, err, ErrCheckoutClaimed)
+	_, err = store.BeginCheckout(ctx, 2)
+	assert.NoError(t, err, "claims are per user")
+
+	require.NoError(t, store.Add(ctx, 1, 10, 3))
+	require.NoError(t, store.Add(ctx, 1, 30, 1))
+	require.NoError(t, store.CompleteCheckout(ctx, 1))
+
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: 3}, {ProductID: 30, Quantity: 1}}, items, "changes made during checkout are kept")
+
+	next, err := store.BeginCheckout(ctx, 1)
+	require.NoError(t, err, "completing drops the claim")
+	assert.NotEqual(t, checkout.ID, next.ID)
+	require.NoError(t, store.ReleaseCheckout(ctx, 1))
+	_, err = store.BeginCheckout(ctx, 1)
+	require.NoError(t, err, "releasing drops the claim")
+
+	items, err = store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, items, 2, "releasing keeps the cart")
+}
+
+func TestMemoryStore_CheckoutIsResumed(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore(time.Hour, 0)
+
+	require.NoError(t, store.Add(ctx, 1, 10, 2))
+	checkout, err := store.BeginCheckout(ctx, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, store.Add(ctx, 1, 20, 1))
+	*now = now.Add(checkoutClaimTTL)
+
+	resumed, err := store.BeginCheckout(ctx, 1)
+	require.NoError(t, err, "an unfinished checkout is claimed again once its claim expires")
+	assert.Equal(t, checkout.ID, resumed.ID)
+	assert.Equal(t, []Item{{ProductID: 10, Quantity: 2}}, resumed.Items, "the resumed checkout orders what the first one did")
+	assert.True(t, resumed.Resumed)
+
+	_, err = store.BeginCheckout(ctx, 1)
+	assert.ErrorIs(t, err, ErrCheckoutClaimed)
+
+	require.NoError(t, store.CompleteCheckout(ctx, 1))
+	items, err := store.Items(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Item{{ProductID: 20, Quantity: 1}}, items)
+}
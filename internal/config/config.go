@@ -1,129 +1,534 @@
 package config
 
 import (
+	tlsconfig "ecomGateway/internal/lib/tls_config"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// Config is read from an optional YAML file and then overridden by the
+// environment variables named in the env tags. Nested structs add their
+// env-prefix to the names of their fields.
 type Config struct {
-	Env            string
-	UserTarget     string
-	UserTimeout    time.Duration
-	UserRetries    int
-	OrderTarget    string
-	OrderTimeout   time.Duration
-	OrderRetries   int
-	ProductTarget  string
-	ProductTimeout time.Duration
-	ProductRetries int
-	HttpAddress    string
-	HttpTimeout    time.Duration
-	IdleTimeout    time.Duration
+	Env  string     `yaml:"env" env:"ENV"`
+	HTTP HTTPConfig `yaml:"http"`
+
+	User    BackendConfig `yaml:"user" env-prefix:"USER_"`
+	Order   BackendConfig `yaml:"order" env-prefix:"ORDER_"`
+	Product BackendConfig `yaml:"product" env-prefix:"PRODUCT_"`
+
+	Retry     RetryConfig     `yaml:"retry" env-prefix:"RETRY_"`
+	Breaker   BreakerConfig   `yaml:"breaker" env-prefix:"BREAKER_"`
+	JWT       JWTConfig       `yaml:"jwt" env-prefix:"JWT_"`
+	Readiness ReadinessConfig `yaml:"readiness" env-prefix:"READINESS_"`
+	Tracing   TracingConfig   `yaml:"tracing" env-prefix:"TRACING_"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Lockout   LockoutConfig   `yaml:"lockout"`
+
+	Idempotency  IdempotencyConfig  `yaml:"idempotency" env-prefix:"IDEMPOTENCY_"`
+	ProductCache CacheConfig        `yaml:"product_cache" env-prefix:"PRODUCT_CACHE_"`
+	CacheControl CacheControlConfig `yaml:"cache_control" env-prefix:"CACHE_CONTROL_"`
+	Cart         CartConfig         `yaml:"cart" env-prefix:"CART_"`
+
+	AdminUserIDs []int64 `yaml:"admin_user_ids" env:"ADMIN_USER_IDS"`
+}
+
+type HTTPConfig struct {
+	Address     string        `yaml:"address" env:"HTTP_ADDRESS"`
+	Timeout     time.Duration `yaml:"timeout" env:"HTTP_TIMEOUT"`
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"IDLE_TIMEOUT"`
+	// ShutdownTimeout bounds how long in-flight requests may drain after
+	// SIGINT/SIGTERM before the server is closed forcibly.
+	ShutdownTimeout time.Duration   `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	TLS             ServerTLSConfig `yaml:"tls" env-prefix:"HTTP_TLS_"`
+}
+
+// ServerTLSConfig terminates TLS on the gateway. MinVersion is "1.2" or
+// "1.3"; CipherSuites takes Go cipher suite names and only applies to TLS 1.2.
+// With RedirectAddress set, a plain HTTP listener there redirects to HTTPS.
+type ServerTLSConfig struct {
+	Enabled         bool     `yaml:"enabled" env:"ENABLED"`
+	CertFile        string   `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile         string   `yaml:"key_file" env:"KEY_FILE"`
+	MinVersion      string   `yaml:"min_version" env:"MIN_VERSION"`
+	CipherSuites    []string `yaml:"cipher_suites" env:"CIPHER_SUITES"`
+	RedirectAddress string   `yaml:"redirect_address" env:"REDIRECT_ADDRESS"`
+}
+
+// BackendConfig describes the connection to one backend gRPC service.
+type BackendConfig struct {
+	Target  string        `yaml:"target" env:"TARGET"`
+	Timeout time.Duration `yaml:"timeout" env:"TIMEOUT"`
+	// Retries is the number of attempts a retryable call gets, the first one
+	// included; 0 and 1 both send it once.
+	Retries int       `yaml:"retries" env:"RETRIES"`
+	TLS     TLSConfig `yaml:"tls" env-prefix:"TLS_"`
+}
+
+// TLSConfig secures the connection to a backend. Without CAFile the system
+// roots are used; CertFile and KeyFile enable mutual TLS.
+type TLSConfig struct {
+	Enabled    bool   `yaml:"enabled" env:"ENABLED"`
+	CAFile     string `yaml:"ca_file" env:"CA_FILE"`
+	CertFile   string `yaml:"cert_file" env:"CERT_FILE"`
+	KeyFile    string `yaml:"key_file" env:"KEY_FILE"`
+	ServerName string `yaml:"server_name" env:"SERVER_NAME"`
+}
+
+// RetryConfig shapes the exponential backoff between retries of idempotent
+// backend calls.
+type RetryConfig struct {
+	Backoff    time.Duration `yaml:"backoff" env:"BACKOFF"`
+	MaxBackoff time.Duration `yaml:"max_backoff" env:"MAX_BACKOFF"`
+	Jitter     float64       `yaml:"jitter" env:"JITTER"`
+}
+
+// BreakerConfig is applied to the circuit breaker of every backend; a zero
+// FailureRatio disables the breakers.
+type BreakerConfig struct {
+	FailureRatio     float64       `yaml:"failure_ratio" env:"FAILURE_RATIO"`
+	MinRequests      int           `yaml:"min_requests" env:"MIN_REQUESTS"`
+	Window           time.Duration `yaml:"window" env:"WINDOW"`
+	Cooldown         time.Duration `yaml:"cooldown" env:"COOLDOWN"`
+	HalfOpenRequests int           `yaml:"half_open_requests" env:"HALF_OPEN_REQUESTS"`
+}
+
+// JWTConfig configures the RSA key used to verify access tokens; the file
+// path takes precedence.
+type JWTConfig struct {
+	PublicKeyPath string `yaml:"public_key_path" env:"PUBLIC_KEY_PATH"`
+	PublicKeyPEM  string `yaml:"public_key" env:"PUBLIC_KEY"`
+}
+
+// ReadinessConfig bounds a /readyz probe; with HealthRPC the probe also calls
+// grpc.health.v1 on every backend. Backends listed in Optional ("user",
+// "order", "product") do not fail readiness.
+type ReadinessConfig struct {
+	Timeout   time.Duration `yaml:"timeout" env:"TIMEOUT"`
+	HealthRPC bool          `yaml:"health_rpc" env:"HEALTH_RPC"`
+	Optional  []string      `yaml:"optional" env:"OPTIONAL"`
+}
+
+// TracingConfig selects the span exporter: "none", "stdout" or "otlp"; spans
+// are sent to Endpoint over OTLP/gRPC when it is "otlp".
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" env:"EXPORTER"`
+	Endpoint    string  `yaml:"otlp_endpoint" env:"OTLP_ENDPOINT"`
+	Insecure    bool    `yaml:"otlp_insecure" env:"OTLP_INSECURE"`
+	SampleRatio float64 `yaml:"sample_ratio" env:"SAMPLE_RATIO"`
+}
+
+// RateLimitConfig holds token bucket limits in requests per second; a zero
+// rate disables the limit. X-Forwarded-For is trusted only from
+// TrustedProxies (CIDRs or IPs).
+type RateLimitConfig struct {
+	RPS            float64  `yaml:"rps" env:"RATE_LIMIT_RPS"`
+	Burst          int      `yaml:"burst" env:"RATE_LIMIT_BURST"`
+	AuthRPS        float64  `yaml:"auth_rps" env:"AUTH_RATE_LIMIT_RPS"`
+	AuthBurst      int      `yaml:"auth_burst" env:"AUTH_RATE_LIMIT_BURST"`
+	UserRPS        float64  `yaml:"user_rps" env:"USER_RATE_LIMIT_RPS"`
+	UserBurst      int      `yaml:"user_burst" env:"USER_RATE_LIMIT_BURST"`
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// LockoutConfig delays failed logins progressively from BaseDelay up to
+// MaxDelay; after the threshold of consecutive failures per login or per IP
+// further attempts are rejected for Duration. A zero threshold disables it.
+type LockoutConfig struct {
+	LoginThreshold int           `yaml:"login_threshold" env:"LOGIN_LOCKOUT_THRESHOLD"`
+	IPThreshold    int           `yaml:"ip_threshold" env:"IP_LOCKOUT_THRESHOLD"`
+	Duration       time.Duration `yaml:"duration" env:"LOCKOUT_DURATION"`
+	BaseDelay      time.Duration `yaml:"base_delay" env:"LOCKOUT_BASE_DELAY"`
+	MaxDelay       time.Duration `yaml:"max_delay" env:"LOCKOUT_MAX_DELAY"`
+}
+
+// IdempotencyConfig sets how long responses to requests with an
+// Idempotency-Key are kept for replay; zero disables idempotency keys.
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"TTL"`
+}
+
+// CacheConfig bounds the in-memory cache of product reads; a zero Size or
+// TTL disables it.
+type CacheConfig struct {
+	Size int           `yaml:"size" env:"SIZE"`
+	TTL  time.Duration `yaml:"ttl" env:"TTL"`
+}
+
+// CacheControlConfig holds the Cache-Control header of each read route;
+// Order covers both GET /orders/{id} and its /details view. An empty value
+// omits the header.
+type CacheControlConfig struct {
+	Products string `yaml:"products" env:"PRODUCTS"`
+	Product  string `yaml:"product" env:"PRODUCT"`
+	Orders   string `yaml:"orders" env:"ORDERS"`
+	Order    string `yaml:"order" env:"ORDER"`
+}
+
+// CartConfig bounds the in-memory shopping carts: a cart is dropped TTL
+// after its last change and holds at most MaxItems distinct products.
+type CartConfig struct {
+	TTL      time.Duration `yaml:"ttl" env:"TTL"`
+	MaxItems int           `yaml:"max_items" env:"MAX_ITEMS"`
 }
 
 const (
-	defaultTimeout = 2 * time.Second
-	defaultRetries = 3
+	defaultTimeout         = 2 * time.Second
+	defaultIdleTimeout     = 60 * time.Second
+	defaultTLSMinVersion   = "1.2"
+	defaultRetries         = 3
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 2 * time.Second
+	defaultRetryJitter     = 0.2
+	defaultShutdownTimeout = 10 * time.Second
+	defaultReadyTimeout    = 2 * time.Second
+	defaultTracingExporter = "none"
+	defaultTracingRatio    = 1.0
+	defaultRateLimitRPS    = 10
+	defaultRateLimitBurst  = 20
+	defaultAuthRPS         = 0.1
+	defaultAuthBurst       = 5
+	defaultUserRPS         = 20
+	defaultUserBurst       = 40
+	defaultLoginLockout    = 5
+	defaultIPLockout       = 20
+	defaultLockoutDuration = 15 * time.Minute
+	defaultLockoutDelay    = 250 * time.Millisecond
+	defaultLockoutMaxDelay = time.Second
+	defaultBreakerRatio    = 0.5
+	defaultBreakerMinReqs  = 10
+	defaultBreakerWindow   = 10 * time.Second
+	defaultBreakerCooldown = 5 * time.Second
+	defaultBreakerProbes   = 1
+	defaultIdempotencyTTL  = 24 * time.Hour
+	defaultCacheSize       = 1000
+	defaultCacheTTL        = 30 * time.Second
+	defaultPublicCaching   = "public, max-age=30"
+	defaultPrivateCaching  = "private, no-cache"
+	defaultCartTTL         = 7 * 24 * time.Hour
+	defaultCartMaxItems    = 100
 )
 
+func defaultConfig() *Config {
+	backend := BackendConfig{Timeout: defaultTimeout, Retries: defaultRetries}
+
+	return &Config{
+		HTTP: HTTPConfig{
+			Timeout:         defaultTimeout,
+			IdleTimeout:     defaultIdleTimeout,
+			ShutdownTimeout: defaultShutdownTimeout,
+			TLS:             ServerTLSConfig{MinVersion: defaultTLSMinVersion},
+		},
+		User:    backend,
+		Order:   backend,
+		Product: backend,
+		Retry: RetryConfig{
+			Backoff:    defaultRetryBackoff,
+			MaxBackoff: defaultRetryMaxBackoff,
+			Jitter:     defaultRetryJitter,
+		},
+		Breaker: BreakerConfig{
+			FailureRatio:     defaultBreakerRatio,
+			MinRequests:      defaultBreakerMinReqs,
+			Window:           defaultBreakerWindow,
+			Cooldown:         defaultBreakerCooldown,
+			HalfOpenRequests: defaultBreakerProbes,
+		},
+		Readiness: ReadinessConfig{Timeout: defaultReadyTimeout},
+		Tracing: TracingConfig{
+			Exporter:    defaultTracingExporter,
+			SampleRatio: defaultTracingRatio,
+		},
+		RateLimit: RateLimitConfig{
+			RPS:       defaultRateLimitRPS,
+			Burst:     defaultRateLimitBurst,
+			AuthRPS:   defaultAuthRPS,
+			AuthBurst: defaultAuthBurst,
+			UserRPS:   defaultUserRPS,
+			UserBurst: defaultUserBurst,
+		},
+		Lockout: LockoutConfig{
+			LoginThreshold: defaultLoginLockout,
+			IPThreshold:    defaultIPLockout,
+			Duration:       defaultLockoutDuration,
+			BaseDelay:      defaultLockoutDelay,
+			MaxDelay:       defaultLockoutMaxDelay,
+		},
+		Idempotency:  IdempotencyConfig{TTL: defaultIdempotencyTTL},
+		ProductCache: CacheConfig{Size: defaultCacheSize, TTL: defaultCacheTTL},
+		CacheControl: CacheControlConfig{
+			Products: defaultPublicCaching,
+			Product:  defaultPublicCaching,
+			Orders:   defaultPrivateCaching,
+			Order:    defaultPrivateCaching,
+		},
+		Cart: CartConfig{TTL: defaultCartTTL, MaxItems: defaultCartMaxItems},
+	}
+}
+
+// MustLoad loads the config from the file given by the -config flag or
+// CONFIG_PATH, if any, and the environment, and exits listing every problem
+// if it is invalid.
 func MustLoad() *Config {
-	env := os.Getenv("ENV")
-	if env == "" {
-		log.Fatal("ENV is not set")
+	cfg, err := Load(configPath(), os.LookupEnv)
+	if err != nil {
+		log.Fatalf("FATAL: invalid configuration:\n%v", err)
 	}
+	return cfg
+}
 
-	httpAddress := os.Getenv("HTTP_ADDRESS")
-	if env == "" {
-		log.Fatal("HTTP_ADDRESS is not set")
+func configPath() string {
+	var path string
+	flag.StringVar(&path, "config", "", "path to the YAML config file")
+	flag.Parse()
+
+	if path == "" {
+		path = os.Getenv("CONFIG_PATH")
 	}
+	return path
+}
 
-	httpTimeoutStr := os.Getenv("HTTP_TIMEOUT")
-	httpTimeout := setTimeout(httpTimeoutStr)
+// Load builds the config from the defaults, the YAML file at path (skipped if
+// path is empty) and the variables returned by lookupEnv, in that order.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := defaultConfig()
 
-	idleTimeoutStr := os.Getenv("IDLE_TIMEOUT")
-	idleTimeout := setTimeout(idleTimeoutStr)
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
 
-	userTarget := os.Getenv("USER_TARGET")
-	if userTarget == "" {
-		log.Fatal("FATAL: USER_TARGET is not set")
+	errs := applyEnv(reflect.ValueOf(cfg).Elem(), "", lookupEnv)
+	errs = append(errs, cfg.validate()...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
 
-	userTimeoutStr := os.Getenv("USER_TIMEOUT")
-	userTimeout := setTimeout(userTimeoutStr)
+	return cfg, nil
+}
 
-	userRetriesStr := os.Getenv("USER_RETRIES")
-	userRetries := setRetries(userRetriesStr)
+var durationType = reflect.TypeOf(time.Duration(0))
 
-	orderTarget := os.Getenv("ORDER_TARGET")
-	if orderTarget == "" {
-		log.Fatal("FATAL: ORDER_TARGET is not set")
-	}
+// applyEnv overrides the fields of v that have their env variable set.
+func applyEnv(v reflect.Value, prefix string, lookupEnv func(string) (string, bool)) []error {
+	var errs []error
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
 
-	orderTimeoutStr := os.Getenv("ORDER_TIMEOUT")
-	orderTimeout := setTimeout(orderTimeoutStr)
+		if field.Type.Kind() == reflect.Struct {
+			errs = append(errs, applyEnv(value, prefix+field.Tag.Get("env-prefix"), lookupEnv)...)
+			continue
+		}
 
-	orderRetriesStr := os.Getenv("ORDER_RETRIES")
-	orderRetries := setRetries(orderRetriesStr)
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		name = prefix + name
 
-	productTarget := os.Getenv("PRODUCT_TARGET")
-	if productTarget == "" {
-		log.Fatal("FATAL: PRODUCT_TARGET is not set")
+		raw, ok := lookupEnv(name)
+		if !ok || raw == "" {
+			continue
+		}
+		if err := setField(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid value %q: %w", name, raw, err))
+		}
 	}
 
-	productTimeoutStr := os.Getenv("PRODUCT_TIMEOUT")
-	productTimeout := setTimeout(productTimeoutStr)
+	return errs
+}
 
-	productRetriesStr := os.Getenv("PRODUCT_RETRIES")
-	productRetries := setRetries(productRetriesStr)
+func setField(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
 
-	return &Config{
-		Env:            env,
-		HttpAddress:    httpAddress,
-		HttpTimeout:    httpTimeout,
-		IdleTimeout:    idleTimeout,
-		UserTarget:     userTarget,
-		UserTimeout:    userTimeout,
-		UserRetries:    userRetries,
-		OrderTarget:    orderTarget,
-		OrderTimeout:   orderTimeout,
-		OrderRetries:   orderRetries,
-		ProductTarget:  productTarget,
-		ProductTimeout: productTimeout,
-		ProductRetries: productRetries,
-	}
-}
-
-func setTimeout(strTimeout string) time.Duration {
-	var timeout time.Duration
-	if strTimeout == "" {
-		timeout := defaultTimeout
-		log.Printf("INFO: USER_TIMEOUT not set, using default value: %s", timeout.String())
-	} else {
-		var err error
-		timeout, err = time.ParseDuration(strTimeout)
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
 		if err != nil {
-			log.Fatalf("FATAL: Invalid format for USER_TIMEOUT ('%s'): %v", strTimeout, err)
+			return err
 		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Slice:
+		items := splitList(raw)
+		slice := reflect.MakeSlice(value.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setField(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
 	}
-	return timeout
+	return nil
 }
 
-func setRetries(strRetries string) int {
-	var retries int
-	if strRetries == "" {
-		retries = defaultRetries
-		log.Printf("INFO: USER_RETRIES not set, using default value: %d", retries)
-	} else {
-		var err error
-		retries, err = strconv.Atoi(strRetries)
-		if err != nil {
-			log.Fatalf("FATAL: Invalid format for USER_RETRIES ('%s'): %v", strRetries, err)
+func splitList(raw string) []string {
+	var items []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// validate reports every problem at once so that a broken deployment can be
+// fixed in one go.
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env != "", "env (ENV) is not set")
+	check(c.HTTP.Address != "", "http.address (HTTP_ADDRESS) is not set")
+	check(c.HTTP.Timeout > 0, "http.timeout must be positive, got %s", c.HTTP.Timeout)
+	check(c.HTTP.IdleTimeout > 0, "http.idle_timeout must be positive, got %s", c.HTTP.IdleTimeout)
+	check(c.HTTP.ShutdownTimeout > 0, "http.shutdown_timeout must be positive, got %s", c.HTTP.ShutdownTimeout)
+	if c.HTTP.TLS.Enabled {
+		check(c.HTTP.TLS.CertFile != "" && c.HTTP.TLS.KeyFile != "",
+			"http.tls.cert_file (HTTP_TLS_CERT_FILE) and http.tls.key_file (HTTP_TLS_KEY_FILE) must be set when TLS is enabled")
+		if _, err := tlsconfig.ParseVersion(c.HTTP.TLS.MinVersion); err != nil {
+			check(false, "http.tls.min_version: %v", err)
+		}
+		if _, err := tlsconfig.ParseCipherSuites(c.HTTP.TLS.CipherSuites); err != nil {
+			check(false, "http.tls.cipher_suites: %v", err)
 		}
-		if retries < 0 {
-			log.Fatalf("FATAL: USER_RETRIES must be a non-negative integer, got: %d", retries)
+		check(c.HTTP.TLS.RedirectAddress != c.HTTP.Address, "http.tls.redirect_address must differ from http.address")
+	} else {
+		check(c.HTTP.TLS.RedirectAddress == "", "http.tls.redirect_address is given but http.tls.enabled is false")
+	}
+
+	errs = append(errs, c.User.validate("user")...)
+	errs = append(errs, c.Order.validate("order")...)
+	errs = append(errs, c.Product.validate("product")...)
+
+	check(c.Retry.Backoff >= 0, "retry.backoff must not be negative, got %s", c.Retry.Backoff)
+	check(c.Retry.MaxBackoff >= 0, "retry.max_backoff must not be negative, got %s", c.Retry.MaxBackoff)
+	check(c.Retry.Jitter >= 0 && c.Retry.Jitter <= 1, "retry.jitter must be between 0 and 1, got %g", c.Retry.Jitter)
+
+	check(c.Breaker.FailureRatio >= 0 && c.Breaker.FailureRatio <= 1, "breaker.failure_ratio must be between 0 and 1, got %g", c.Breaker.FailureRatio)
+	check(c.Breaker.MinRequests >= 0, "breaker.min_requests must not be negative, got %d", c.Breaker.MinRequests)
+	check(c.Breaker.Window >= 0, "breaker.window must not be negative, got %s", c.Breaker.Window)
+	check(c.Breaker.Cooldown >= 0, "breaker.cooldown must not be negative, got %s", c.Breaker.Cooldown)
+	check(c.Breaker.HalfOpenRequests >= 0, "breaker.half_open_requests must not be negative, got %d", c.Breaker.HalfOpenRequests)
+
+	check(c.JWT.PublicKeyPath != "" || c.JWT.PublicKeyPEM != "",
+		"jwt.public_key_path (JWT_PUBLIC_KEY_PATH) or jwt.public_key (JWT_PUBLIC_KEY) must be set")
+
+	for _, id := range c.AdminUserIDs {
+		check(id > 0, "admin_user_ids: invalid user id %d", id)
+	}
+
+	check(c.Readiness.Timeout > 0, "readiness.timeout must be positive, got %s", c.Readiness.Timeout)
+	for _, name := range c.Readiness.Optional {
+		check(name == "user" || name == "order" || name == "product", "readiness.optional: unknown backend %q", name)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		check(c.Tracing.Endpoint != "", "tracing.otlp_endpoint (TRACING_OTLP_ENDPOINT) must be set when the exporter is otlp")
+	default:
+		check(false, "tracing.exporter: unknown exporter %q", c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	check(c.RateLimit.RPS >= 0, "rate_limit.rps must not be negative, got %g", c.RateLimit.RPS)
+	check(c.RateLimit.Burst >= 0, "rate_limit.burst must not be negative, got %d", c.RateLimit.Burst)
+	check(c.RateLimit.AuthRPS >= 0, "rate_limit.auth_rps must not be negative, got %g", c.RateLimit.AuthRPS)
+	check(c.RateLimit.AuthBurst >= 0, "rate_limit.auth_burst must not be negative, got %d", c.RateLimit.AuthBurst)
+	check(c.RateLimit.UserRPS >= 0, "rate_limit.user_rps must not be negative, got %g", c.RateLimit.UserRPS)
+	check(c.RateLimit.UserBurst >= 0, "rate_limit.user_burst must not be negative, got %d", c.RateLimit.UserBurst)
+	for _, proxy := range c.RateLimit.TrustedProxies {
+		_, prefixErr := netip.ParsePrefix(proxy)
+		_, addrErr := netip.ParseAddr(proxy)
+		check(prefixErr == nil || addrErr == nil, "rate_limit.trusted_proxies: invalid CIDR or IP %q", proxy)
+	}
+
+	check(c.Lockout.LoginThreshold >= 0, "lockout.login_threshold must not be negative, got %d", c.Lockout.LoginThreshold)
+	check(c.Lockout.IPThreshold >= 0, "lockout.ip_threshold must not be negative, got %d", c.Lockout.IPThreshold)
+	check(c.Lockout.Duration >= 0, "lockout.duration must not be negative, got %s", c.Lockout.Duration)
+	check(c.Lockout.BaseDelay >= 0, "lockout.base_delay must not be negative, got %s", c.Lockout.BaseDelay)
+	check(c.Lockout.MaxDelay >= 0, "lockout.max_delay must not be negative, got %s", c.Lockout.MaxDelay)
+	if c.Lockout.LoginThreshold > 0 || c.Lockout.IPThreshold > 0 {
+		check(c.Lockout.Duration > 0, "lockout.duration must be positive when a lockout threshold is set, got %s", c.Lockout.Duration)
+	}
+	// Failed logins sleep inside the request, so an uncapped or long delay
+	// would outlast the server's write timeout.
+	if c.Lockout.BaseDelay > 0 {
+		check(c.Lockout.MaxDelay > 0 && c.Lockout.MaxDelay < c.HTTP.Timeout,
+			"lockout.max_delay must be positive and shorter than http.timeout (%s), got %s", c.HTTP.Timeout, c.Lockout.MaxDelay)
+	}
+
+	check(c.Idempotency.TTL >= 0, "idempotency.ttl must not be negative, got %s", c.Idempotency.TTL)
+
+	check(c.ProductCache.Size >= 0, "product_cache.size must not be negative, got %d", c.ProductCache.Size)
+	check(c.ProductCache.TTL >= 0, "product_cache.ttl must not be negative, got %s", c.ProductCache.TTL)
+
+	check(c.Cart.TTL > 0, "cart.ttl must be positive, got %s", c.Cart.TTL)
+	check(c.Cart.MaxItems > 0, "cart.max_items must be positive, got %d", c.Cart.MaxItems)
+
+	return errs
+}
+
+func (b BackendConfig) validate(name string) []error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(name+"."+format, args...))
 		}
 	}
-	return retries
+
+	check(b.Target != "", "target (%s_TARGET) is not set", strings.ToUpper(name))
+	check(b.Timeout > 0, "timeout must be positive, got %s", b.Timeout)
+	check(b.Retries >= 0, "retries must not be negative, got %d", b.Retries)
+	check((b.TLS.CertFile == "") == (b.TLS.KeyFile == ""), "tls.cert_file and tls.key_file must be set together")
+	check(b.TLS.Enabled || (b.TLS.CAFile == "" && b.TLS.CertFile == "" && b.TLS.ServerName == ""),
+		"tls settings are given but tls.enabled is false")
+
+	return errs
 }
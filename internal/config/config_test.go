@@ -0,0 +1,194 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// minimalEnv is the smallest valid configuration.
+func minimalEnv() map[string]string {
+	return map[string]string{
+		"ENV":             "local",
+		"HTTP_ADDRESS":    ":8080",
+		"USER_TARGET":     "user:50051",
+		"ORDER_TARGET":    "order:50051",
+		"PRODUCT_TARGET":  "product:50051",
+		"JWT_PUBLIC_KEY":  "pem",
+		"ADMIN_USER_IDS":  "1, 2",
+		"TRUSTED_PROXIES": "10.0.0.0/8,192.168.1.1",
+	}
+}
+
+func TestLoad_EnvOnlyAppliesDefaults(t *testing.T) {
+	cfg, err := Load("", envMap(minimalEnv()))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8080", cfg.HTTP.Address)
+	assert.Equal(t, defaultTimeout, cfg.HTTP.Timeout, "unset timeouts fall back to the default")
+	assert.Equal(t, defaultIdleTimeout, cfg.HTTP.IdleTimeout)
+	assert.Equal(t, defaultTimeout, cfg.User.Timeout)
+	assert.Equal(t, defaultRetries, cfg.Product.Retries)
+	assert.Equal(t, defaultRetryJitter, cfg.Retry.Jitter)
+	assert.Equal(t, []int64{1, 2}, cfg.AdminUserIDs)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.RateLimit.TrustedProxies)
+}
+
+func TestLoad_FileWithEnvOverrides(t *testing.T) {
+	path := writeConfig(t, `
+env: prod
+http:
+  address: ":9000"
+  timeout: 5s
+user:
+  target: user.internal:443
+  timeout: 3s
+  retries: 1
+  tls:
+    enabled: true
+    ca_file: /etc/gateway/ca.pem
+    server_name: user.internal
+order:
+  target: order.internal:443
+product:
+  target: product.internal:443
+jwt:
+  public_key_path: /etc/gateway/jwt.pem
+readiness:
+  optional: [order]
+`)
+
+	cfg, err := Load(path, envMap(map[string]string{
+		"HTTP_ADDRESS":  ":9443",
+		"USER_RETRIES":  "5",
+		"ORDER_TIMEOUT": "750ms",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "prod", cfg.Env)
+	assert.Equal(t, ":9443", cfg.HTTP.Address, "env overrides the file")
+	assert.Equal(t, 5*time.Second, cfg.HTTP.Timeout)
+	assert.Equal(t, "user.internal:443", cfg.User.Target)
+	assert.Equal(t, 3*time.Second, cfg.User.Timeout)
+	assert.Equal(t, 5, cfg.User.Retries)
+	assert.True(t, cfg.User.TLS.Enabled)
+	assert.Equal(t, "/etc/gateway/ca.pem", cfg.User.TLS.CAFile)
+	assert.Equal(t, "user.internal", cfg.User.TLS.ServerName)
+	assert.Equal(t, 750*time.Millisecond, cfg.Order.Timeout)
+	assert.Equal(t, defaultRetries, cfg.Order.Retries, "fields missing from the file keep their default")
+	assert.Equal(t, []string{"order"}, cfg.Readiness.Optional)
+}
+
+func TestLoad_ReportsAllErrors(t *testing.T) {
+	env := minimalEnv()
+	delete(env, "HTTP_ADDRESS")
+	delete(env, "PRODUCT_TARGET")
+	env["USER_TIMEOUT"] = "soon"
+	env["RETRY_JITTER"] = "2"
+	env["READINESS_OPTIONAL"] = "payments"
+	env["ORDER_TLS_CERT_FILE"] = "/etc/gateway/client.pem"
+	env["SHUTDOWN_TIMEOUT"] = "0s"
+
+	_, err := Load("", envMap(env))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "http.address (HTTP_ADDRESS) is not set")
+	assert.Contains(t, msg, "product.target (PRODUCT_TARGET) is not set")
+	assert.Contains(t, msg, `USER_TIMEOUT: invalid value "soon"`)
+	assert.Contains(t, msg, "retry.jitter must be between 0 and 1")
+	assert.Contains(t, msg, `readiness.optional: unknown backend "payments"`)
+	assert.Contains(t, msg, "order.tls.cert_file and tls.key_file must be set together")
+	assert.Contains(t, msg, "order.tls settings are given but tls.enabled is false")
+	assert.Contains(t, msg, "http.shutdown_timeout must be positive, got 0s")
+}
+
+func TestLoad_LockoutLimits(t *testing.T) {
+	env := minimalEnv()
+	env["HTTP_TIMEOUT"] = "2s"
+	env["LOCKOUT_DURATION"] = "0s"
+	env["LOCKOUT_MAX_DELAY"] = "2s"
+
+	_, err := Load("", envMap(env))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "lockout.duration must be positive when a lockout threshold is set")
+	assert.Contains(t, msg, "lockout.max_delay must be positive and shorter than http.timeout (2s), got 2s")
+
+	env["LOGIN_LOCKOUT_THRESHOLD"] = "0"
+	env["IP_LOCKOUT_THRESHOLD"] = "0"
+	env["LOCKOUT_BASE_DELAY"] = "0s"
+	_, err = Load("", envMap(env))
+	assert.NoError(t, err, "the limits only apply to an enabled lockout")
+}
+
+func TestLoad_ServerTLS(t *testing.T) {
+	env := minimalEnv()
+	env["HTTP_TLS_ENABLED"] = "true"
+	env["HTTP_TLS_CERT_FILE"] = "/etc/gateway/tls.crt"
+	env["HTTP_TLS_KEY_FILE"] = "/etc/gateway/tls.key"
+	env["HTTP_TLS_CIPHER_SUITES"] = "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"
+	env["HTTP_TLS_REDIRECT_ADDRESS"] = ":8081"
+
+	cfg, err := Load("", envMap(env))
+	require.NoError(t, err)
+	assert.Equal(t, defaultTLSMinVersion, cfg.HTTP.TLS.MinVersion)
+	assert.Len(t, cfg.HTTP.TLS.CipherSuites, 2)
+	assert.Equal(t, ":8081", cfg.HTTP.TLS.RedirectAddress)
+
+	env["HTTP_TLS_MIN_VERSION"] = "1.1"
+	env["HTTP_TLS_CIPHER_SUITES"] = "TLS_RSA_WITH_RC4_128_SHA"
+	env["HTTP_TLS_REDIRECT_ADDRESS"] = ":8080"
+	delete(env, "HTTP_TLS_KEY_FILE")
+
+	_, err = Load("", envMap(env))
+	require.Error(t, err)
+
+	msg := err.Error()
+	assert.Contains(t, msg, "http.tls.cert_file (HTTP_TLS_CERT_FILE) and http.tls.key_file (HTTP_TLS_KEY_FILE) must be set")
+	assert.Contains(t, msg, `http.tls.min_version: unsupported TLS version "1.1"`)
+	assert.Contains(t, msg, `http.tls.cipher_suites: unknown or insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+	assert.Contains(t, msg, "http.tls.redirect_address must differ from http.address")
+}
+
+func TestLoad_InvalidFile(t *testing.T) {
+	_, err := Load(writeConfig(t, "http: [not a map"), envMap(minimalEnv()))
+	require.Error(t, err)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.yaml"), envMap(minimalEnv()))
+	require.Error(t, err)
+}
+
+func TestLoad_ExampleConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join("..", "..", "config", "config.example.yaml"), envMap(nil))
+	require.NoError(t, err)
+
+	defaults := defaultConfig()
+	assert.Equal(t, defaults.Retry, cfg.Retry, "the example documents the defaults")
+	assert.Equal(t, defaults.Breaker, cfg.Breaker, "the example documents the defaults")
+	assert.Equal(t, defaults.Lockout, cfg.Lockout, "the example documents the defaults")
+	assert.Equal(t, defaults.Idempotency, cfg.Idempotency, "the example documents the defaults")
+	assert.Equal(t, defaults.ProductCache, cfg.ProductCache, "the example documents the defaults")
+	assert.Equal(t, defaults.Cart, cfg.Cart, "the example documents the defaults")
+	assert.Equal(t, defaults.CacheControl, cfg.CacheControl, "the example documents the defaults")
+}
@@ -0,0 +1,235 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerPolicy configures a circuit breaker. While closed, the breaker opens
+// once at least MinRequests calls were made within Window and FailureRatio of
+// them failed. After Cooldown it lets HalfOpenRequests probe calls through
+// and closes again if all of them succeed. A zero FailureRatio disables it.
+type BreakerPolicy struct {
+	FailureRatio     float64
+	MinRequests      uint
+	Window           time.Duration
+	Cooldown         time.Duration
+	HalfOpenRequests uint
+}
+
+func (p BreakerPolicy) Enabled() bool {
+	return p.FailureRatio > 0
+}
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerHalfOpen
+	BreakerOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerHalfOpen:
+		return "half_open"
+	case BreakerOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a circuit breaker for the calls to one backend.
+type Breaker struct {
+	name          string
+	policy        BreakerPolicy
+	onStateChange func(name string, from, to BreakerState)
+	now           func() time.Time
+
+	mu          sync.Mutex
+	state       BreakerState
+	generation  uint64
+	windowStart time.Time
+	requests    uint
+	failures    uint
+	openedAt    time.Time
+	probes      uint
+	successes   uint
+}
+
+// NewBreaker creates a closed breaker. onStateChange, if set, is called on
+// every transition with the breaker lock held, so it must not block.
+func NewBreaker(name string, policy BreakerPolicy, onStateChange func(name string, from, to BreakerState)) *Breaker {
+	if policy.HalfOpenRequests == 0 {
+		policy.HalfOpenRequests = 1
+	}
+
+	return &Breaker{
+		name:          name,
+		policy:        policy,
+		onStateChange: onStateChange,
+		now:           time.Now,
+		windowStart:   time.Now(),
+	}
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	return b.state
+}
+
+const breakerOpenPrefix = "circuit breaker for "
+
+// IsBreakerOpen reports whether err is the rejection of a call that an open
+// breaker never let through to the backend, as opposed to a backend that
+// itself answered Unavailable.
+func IsBreakerOpen(err error) bool {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) || se.GRPCStatus() == nil {
+		return false
+	}
+	st := se.GRPCStatus()
+	return st.Code() == codes.Unavailable && strings.HasPrefix(st.Message(), breakerOpenPrefix)
+}
+
+// UnaryClientInterceptor fails calls fast with Unavailable while the breaker
+// is open. It belongs before the retry interceptor so that a call counts once
+// however many attempts it took. Health checks bypass the breaker so that
+// readiness probes see the backend itself.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !b.policy.Enabled() || method == healthpb.Health_Check_FullMethodName {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		generation, ok := b.allow()
+		if !ok {
+			return status.Errorf(codes.Unavailable, breakerOpenPrefix+"%s is open", b.name)
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(generation, err)
+		return err
+	}
+}
+
+func (b *Breaker) allow() (uint64, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+
+	switch b.state {
+	case BreakerOpen:
+		return 0, false
+	case BreakerHalfOpen:
+		if b.probes >= b.policy.HalfOpenRequests {
+			return 0, false
+		}
+		b.probes++
+	}
+	return b.generation, true
+}
+
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// The result of a call started before the last transition says nothing
+	// about the current state.
+	if generation != b.generation {
+		return
+	}
+
+	code := status.Code(err)
+	if code == codes.Canceled {
+		if b.state == BreakerHalfOpen {
+			b.probes--
+		}
+		return
+	}
+	failed := isBreakerFailure(code)
+
+	switch b.state {
+	case BreakerClosed:
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if b.requests >= b.policy.MinRequests &&
+			float64(b.failures)/float64(b.requests) >= b.policy.FailureRatio {
+			b.setState(BreakerOpen)
+		}
+	case BreakerHalfOpen:
+		if failed {
+			b.setState(BreakerOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.policy.HalfOpenRequests {
+			b.setState(BreakerClosed)
+		}
+	}
+}
+
+// advance applies the transitions that only depend on time.
+func (b *Breaker) advance(now time.Time) {
+	switch b.state {
+	case BreakerClosed:
+		if b.policy.Window > 0 && now.Sub(b.windowStart) >= b.policy.Window {
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	case BreakerOpen:
+		if now.Sub(b.openedAt) >= b.policy.Cooldown {
+			b.setState(BreakerHalfOpen)
+		}
+	}
+}
+
+func (b *Breaker) setState(state BreakerState) {
+	from := b.state
+	now := b.now()
+
+	b.state = state
+	b.generation++
+	b.requests, b.failures = 0, 0
+	b.probes, b.successes = 0, 0
+	b.windowStart = now
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+
+	if b.onStateChange != nil {
+		b.onStateChange(b.name, from, state)
+	}
+}
+
+// isBreakerFailure reports whether code points at an unhealthy backend rather
+// than at a bad request.
+func isBreakerFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
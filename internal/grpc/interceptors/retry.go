@@ -0,0 +1,76 @@
+package interceptors
+
+import (
+	"context"
+	"time"
+
+	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// IdempotencyKeyMetadata is the outgoing metadata key that marks a mutating
+// call as safe to retry: the backend deduplicates requests carrying it.
+const IdempotencyKeyMetadata = "idempotency-key"
+
+// RetryPolicy configures how idempotent calls are retried. Retries happen
+// only on Unavailable and DeadlineExceeded, waiting Backoff*2^attempt with
+// the given jitter fraction, capped at MaxBackoff.
+type RetryPolicy struct {
+	MaxRetries uint
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	Jitter     float64
+}
+
+// WithIdempotencyKey attaches key to the outgoing metadata, allowing a
+// mutating call made with ctx to be retried.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, IdempotencyKeyMetadata, key)
+}
+
+func hasIdempotencyKey(ctx context.Context) bool {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	return ok && len(md.Get(IdempotencyKeyMetadata)) > 0
+}
+
+// UnaryClientRetry retries calls according to policy, applying timeout to
+// every attempt. Methods listed in mutations (full method names) are sent
+// exactly once unless the call carries an idempotency key.
+func UnaryClientRetry(policy RetryPolicy, timeout time.Duration, mutations ...string) grpc.UnaryClientInterceptor {
+	var backoff grpcretry.BackoffFunc
+	switch {
+	case policy.Backoff <= 0:
+		backoff = grpcretry.BackoffLinear(0)
+	case policy.MaxBackoff > 0:
+		backoff = grpcretry.BackoffExponentialWithJitterBounded(policy.Backoff, policy.Jitter, policy.MaxBackoff)
+	default:
+		backoff = grpcretry.BackoffExponentialWithJitter(policy.Backoff, policy.Jitter)
+	}
+
+	retry := grpcretry.UnaryClientInterceptor(
+		grpcretry.WithCodes(codes.Unavailable, codes.DeadlineExceeded),
+		grpcretry.WithMax(policy.MaxRetries+1),
+		grpcretry.WithPerRetryTimeout(timeout),
+		grpcretry.WithBackoff(backoff),
+	)
+
+	isMutation := make(map[string]bool, len(mutations))
+	for _, method := range mutations {
+		isMutation[method] = true
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if isMutation[method] && !hasIdempotencyKey(ctx) {
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		return retry(ctx, method, req, reply, cc, invoker, opts...)
+	}
+}
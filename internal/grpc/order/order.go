@@ -2,57 +2,50 @@ package ordergrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
+	requestid "ecomGateway/internal/lib/request_id"
+	"ecomGateway/internal/metrics"
+	"ecomGateway/internal/tracing"
 	"fmt"
 	"log/slog"
 	"time"
 
 	order1 "github.com/KuranovNikita/ecomProto/gen/go/order"
-	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Client struct {
-	api order1.OrderServiceClient
-	log *slog.Logger
+	api     order1.OrderServiceClient
+	cc      *grpc.ClientConn
+	breaker *interceptors.Breaker
+	log     *slog.Logger
 }
 
+// New dials the backend in plaintext unless additionalOpts carry transport
+// credentials, which take precedence over the default.
 func New(
 	log *slog.Logger,
 	target string,
 	timeout time.Duration,
-	retriesCount int,
+	retryPolicy interceptors.RetryPolicy,
+	breakerPolicy interceptors.BreakerPolicy,
 	additionalOpts ...grpc.DialOption,
 ) (*Client, error) {
 	const op = "grpc.order.New"
-	// retryOpts := []grpcretry.CallOption{
-	// 	grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
-	// 	grpcretry.WithMax(uint(retriesCount)),
-	// 	grpcretry.WithPerRetryTimeout(timeout),
-	// }
-
-	// cc, err := grpc.NewClient(target,
-	// 	grpc.WithTransportCredentials(insecure.NewCredentials()),
-	// 	grpc.WithChainUnaryInterceptor(
-	// 		grpcretry.UnaryClientInterceptor(retryOpts...),
-	// 	),
-	// )
-	// if err != nil {
-	// 	return nil, fmt.Errorf("%s: %w", op, err)
-	// }
-
-	retryInterceptorOpts := []grpcretry.CallOption{
-		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
-		grpcretry.WithMax(uint(retriesCount)),
-		grpcretry.WithPerRetryTimeout(timeout),
-	}
+
+	breaker := interceptors.NewBreaker("order", breakerPolicy, metrics.BreakerStateChanged)
+	metrics.ObserveBreaker(breaker)
 
 	var dialOpts []grpc.DialOption
 
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(
-		grpcretry.UnaryClientInterceptor(retryInterceptorOpts...),
+		tracing.UnaryClientInterceptor("order"),
+		requestid.UnaryClientInterceptor(),
+		breaker.UnaryClientInterceptor(),
+		interceptors.UnaryClientRetry(retryPolicy, timeout, order1.OrderService_CreateOrder_FullMethodName),
+		metrics.UnaryClientInterceptor("order"),
 	))
 
 	dialOpts = append(dialOpts, additionalOpts...)
@@ -63,10 +56,31 @@ func New(
 	}
 
 	return &Client{
-		api: order1.NewOrderServiceClient(cc),
+		api:     order1.NewOrderServiceClient(cc),
+		cc:      cc,
+		breaker: breaker,
+		log:     log,
 	}, nil
 }
 
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.cc
+}
+
+func (c *Client) Breaker() *interceptors.Breaker {
+	return c.breaker
+}
+
+func (c *Client) Close() error {
+	const op = "grpc.order.close"
+
+	if err := c.cc.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (c *Client) CreateOrder(ctx context.Context, userID int64, items []*order1.OrderItem) (int64, int64, error) {
 	const op = "grpc.order.create_order"
 
@@ -2,6 +2,7 @@ package ordergrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
 	"errors"
 	"log/slog"
 	"net"
@@ -73,7 +74,8 @@ func setupTestOrderGRPCServer(t *testing.T, mockSrv *mockOrderServer) (*Client,
 		slog.Default(),
 		"passthrough:///bufnet",
 		1*time.Second,
-		1,
+		interceptors.RetryPolicy{MaxRetries: 1},
+		interceptors.BreakerPolicy{},
 		grpc.WithContextDialer(bufDialer),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
@@ -2,42 +2,50 @@ package productgrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
+	requestid "ecomGateway/internal/lib/request_id"
+	"ecomGateway/internal/metrics"
+	"ecomGateway/internal/tracing"
 	"fmt"
 	"log/slog"
 	"time"
 
 	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
-	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Client struct {
-	api product1.ProductServiceClient
-	log *slog.Logger
+	api     product1.ProductServiceClient
+	cc      *grpc.ClientConn
+	breaker *interceptors.Breaker
+	log     *slog.Logger
 }
 
+// New dials the backend in plaintext unless additionalOpts carry transport
+// credentials, which take precedence over the default.
 func New(
 	log *slog.Logger,
 	target string,
 	timeout time.Duration,
-	retriesCount int,
+	retryPolicy interceptors.RetryPolicy,
+	breakerPolicy interceptors.BreakerPolicy,
 	additionalOpts ...grpc.DialOption,
 ) (*Client, error) {
 	const op = "grpc.product.New"
 
-	retryInterceptorOpts := []grpcretry.CallOption{
-		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
-		grpcretry.WithMax(uint(retriesCount)),
-		grpcretry.WithPerRetryTimeout(timeout),
-	}
+	breaker := interceptors.NewBreaker("product", breakerPolicy, metrics.BreakerStateChanged)
+	metrics.ObserveBreaker(breaker)
 
 	var dialOpts []grpc.DialOption
 
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(
-		grpcretry.UnaryClientInterceptor(retryInterceptorOpts...),
+		tracing.UnaryClientInterceptor("product"),
+		requestid.UnaryClientInterceptor(),
+		breaker.UnaryClientInterceptor(),
+		interceptors.UnaryClientRetry(retryPolicy, timeout, product1.ProductService_UpdateStock_FullMethodName),
+		metrics.UnaryClientInterceptor("product"),
 	))
 
 	dialOpts = append(dialOpts, additionalOpts...)
@@ -48,10 +56,31 @@ func New(
 	}
 
 	return &Client{
-		api: product1.NewProductServiceClient(cc),
+		api:     product1.NewProductServiceClient(cc),
+		cc:      cc,
+		breaker: breaker,
+		log:     log,
 	}, nil
 }
 
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.cc
+}
+
+func (c *Client) Breaker() *interceptors.Breaker {
+	return c.breaker
+}
+
+func (c *Client) Close() error {
+	const op = "grpc.product.close"
+
+	if err := c.cc.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (c *Client) GetProduct(ctx context.Context, productID int64) (*product1.ProductDetails, error) {
 	const op = "grpc.product.get_product"
 
@@ -2,9 +2,11 @@ package productgrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
 	"errors"
 	"log/slog"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -60,6 +62,12 @@ func (s *mockProductServer) UpdateStock(ctx context.Context, req *product1.Updat
 func setupTestProductGRPCServer(t *testing.T, mockSrv *mockProductServer) (*Client, func()) {
 	t.Helper()
 
+	return setupTestProductGRPCServerWithBreaker(t, mockSrv, interceptors.BreakerPolicy{})
+}
+
+func setupTestProductGRPCServerWithBreaker(t *testing.T, mockSrv *mockProductServer, breakerPolicy interceptors.BreakerPolicy) (*Client, func()) {
+	t.Helper()
+
 	bufSize := 1024 * 1024
 	lis := bufconn.Listen(bufSize)
 
@@ -80,7 +88,8 @@ func setupTestProductGRPCServer(t *testing.T, mockSrv *mockProductServer) (*Clie
 		slog.Default(),
 		"passthrough:///bufnet",
 		1*time.Second,
-		1,
+		interceptors.RetryPolicy{MaxRetries: 1},
+		breakerPolicy,
 		grpc.WithContextDialer(bufDialer),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
@@ -314,3 +323,161 @@ func TestClient_UpdateStock_InsufficientStock(t *testing.T) {
 	assert.Equal(t, codes.FailedPrecondition, st.Code())
 	assert.Contains(t, err.Error(), "grpc.product.update_stock")
 }
+
+func TestClient_GetProduct_RetriesUnavailable(t *testing.T) {
+	mockSrv := &mockProductServer{}
+	client, cleanup := setupTestProductGRPCServer(t, mockSrv)
+	defer cleanup()
+
+	var calls atomic.Int32
+	mockSrv.GetProductFunc = func(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+		if calls.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "backend restarting")
+		}
+		return &product1.GetProductResponse{ProductDetails: &product1.ProductDetails{Id: req.ProductId}}, nil
+	}
+
+	details, err := client.GetProduct(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), details.Id)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_GetProduct_NotFoundIsNotRetried(t *testing.T) {
+	mockSrv := &mockProductServer{}
+	client, cleanup := setupTestProductGRPCServer(t, mockSrv)
+	defer cleanup()
+
+	var calls atomic.Int32
+	mockSrv.GetProductFunc = func(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+		calls.Add(1)
+		return nil, status.Error(codes.NotFound, "product not found")
+	}
+
+	_, err := client.GetProduct(context.Background(), 1)
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_UpdateStock_IsNotRetriedWithoutIdempotencyKey(t *testing.T) {
+	mockSrv := &mockProductServer{}
+	client, cleanup := setupTestProductGRPCServer(t, mockSrv)
+	defer cleanup()
+
+	var calls atomic.Int32
+	mockSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		calls.Add(1)
+		return nil, status.Error(codes.Unavailable, "backend restarting")
+	}
+
+	err := client.UpdateStock(context.Background(), 1, -1)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_UpdateStock_RetriedWithIdempotencyKey(t *testing.T) {
+	mockSrv := &mockProductServer{}
+	client, cleanup := setupTestProductGRPCServer(t, mockSrv)
+	defer cleanup()
+
+	var calls atomic.Int32
+	mockSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		if calls.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "backend restarting")
+		}
+		return &emptypb.Empty{}, nil
+	}
+
+	ctx := interceptors.WithIdempotencyKey(context.Background(), "key-1")
+	err := client.UpdateStock(ctx, 1, -1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_Breaker_OpensAndFailsFast(t *testing.T) {
+	var calls atomic.Int32
+	mockSrv := &mockProductServer{
+		GetProductFunc: func(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+			calls.Add(1)
+			return nil, status.Error(codes.Unavailable, "backend down")
+		},
+	}
+	client, cleanup := setupTestProductGRPCServerWithBreaker(t, mockSrv, interceptors.BreakerPolicy{
+		FailureRatio: 0.5,
+		MinRequests:  2,
+		Window:       time.Minute,
+		Cooldown:     time.Minute,
+	})
+	defer cleanup()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetProduct(context.Background(), 1)
+		require.Error(t, err)
+		assert.False(t, interceptors.IsBreakerOpen(err), "the backend itself answered Unavailable")
+	}
+	assert.Equal(t, interceptors.BreakerOpen, client.Breaker().State())
+	assert.Equal(t, int32(4), calls.Load(), "each call is retried once before the breaker opens")
+
+	_, err := client.GetProduct(context.Background(), 1)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), "circuit breaker")
+	assert.True(t, interceptors.IsBreakerOpen(err))
+	assert.Equal(t, int32(4), calls.Load(), "an open breaker does not reach the backend")
+}
+
+func TestClient_Breaker_IgnoresClientErrors(t *testing.T) {
+	mockSrv := &mockProductServer{
+		GetProductFunc: func(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+			return nil, status.Error(codes.NotFound, "product not found")
+		},
+	}
+	client, cleanup := setupTestProductGRPCServerWithBreaker(t, mockSrv, interceptors.BreakerPolicy{
+		FailureRatio: 0.5,
+		MinRequests:  2,
+		Window:       time.Minute,
+		Cooldown:     time.Minute,
+	})
+	defer cleanup()
+
+	for i := 0; i < 5; i++ {
+		_, err := client.GetProduct(context.Background(), 1)
+		require.Equal(t, codes.NotFound, status.Code(err))
+	}
+	assert.Equal(t, interceptors.BreakerClosed, client.Breaker().State())
+}
+
+func TestClient_Breaker_HalfOpenProbe(t *testing.T) {
+	var healthy atomic.Bool
+	mockSrv := &mockProductServer{
+		GetProductFunc: func(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+			if !healthy.Load() {
+				return nil, status.Error(codes.Unavailable, "backend down")
+			}
+			return &product1.GetProductResponse{ProductDetails: &product1.ProductDetails{Id: req.GetProductId()}}, nil
+		},
+	}
+	client, cleanup := setupTestProductGRPCServerWithBreaker(t, mockSrv, interceptors.BreakerPolicy{
+		FailureRatio: 1,
+		MinRequests:  1,
+		Window:       time.Minute,
+		Cooldown:     50 * time.Millisecond,
+	})
+	defer cleanup()
+
+	_, err := client.GetProduct(context.Background(), 1)
+	require.Error(t, err)
+	require.Equal(t, interceptors.BreakerOpen, client.Breaker().State())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, interceptors.BreakerHalfOpen, client.Breaker().State())
+
+	healthy.Store(true)
+	_, err = client.GetProduct(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, interceptors.BreakerClosed, client.Breaker().State())
+}
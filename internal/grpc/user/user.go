@@ -2,20 +2,24 @@ package usergrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
+	requestid "ecomGateway/internal/lib/request_id"
+	"ecomGateway/internal/metrics"
+	"ecomGateway/internal/tracing"
 	"fmt"
 	"log/slog"
 	"time"
 
 	user1 "github.com/KuranovNikita/ecomProto/gen/go/user"
-	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
 type Client struct {
-	api user1.UserServiceClient
-	log *slog.Logger
+	api     user1.UserServiceClient
+	cc      *grpc.ClientConn
+	breaker *interceptors.Breaker
+	log     *slog.Logger
 }
 
 type UserDetails struct {
@@ -24,26 +28,30 @@ type UserDetails struct {
 	Email  string
 }
 
+// New dials the backend in plaintext unless additionalOpts carry transport
+// credentials, which take precedence over the default.
 func New(
 	log *slog.Logger,
 	target string,
 	timeout time.Duration,
-	retriesCount int,
+	retryPolicy interceptors.RetryPolicy,
+	breakerPolicy interceptors.BreakerPolicy,
 	additionalOpts ...grpc.DialOption,
 ) (*Client, error) {
 	const op = "grpc.user.New"
 
-	retryInterceptorOpts := []grpcretry.CallOption{
-		grpcretry.WithCodes(codes.NotFound, codes.Aborted, codes.DeadlineExceeded),
-		grpcretry.WithMax(uint(retriesCount)),
-		grpcretry.WithPerRetryTimeout(timeout),
-	}
+	breaker := interceptors.NewBreaker("user", breakerPolicy, metrics.BreakerStateChanged)
+	metrics.ObserveBreaker(breaker)
 
 	var dialOpts []grpc.DialOption
 
 	dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	dialOpts = append(dialOpts, grpc.WithChainUnaryInterceptor(
-		grpcretry.UnaryClientInterceptor(retryInterceptorOpts...),
+		tracing.UnaryClientInterceptor("user"),
+		requestid.UnaryClientInterceptor(),
+		breaker.UnaryClientInterceptor(),
+		interceptors.UnaryClientRetry(retryPolicy, timeout, user1.UserService_Register_FullMethodName),
+		metrics.UnaryClientInterceptor("user"),
 	))
 
 	dialOpts = append(dialOpts, additionalOpts...)
@@ -54,11 +62,31 @@ func New(
 	}
 
 	return &Client{
-		api: user1.NewUserServiceClient(cc),
-		log: log,
+		api:     user1.NewUserServiceClient(cc),
+		cc:      cc,
+		breaker: breaker,
+		log:     log,
 	}, nil
 }
 
+func (c *Client) Conn() *grpc.ClientConn {
+	return c.cc
+}
+
+func (c *Client) Breaker() *interceptors.Breaker {
+	return c.breaker
+}
+
+func (c *Client) Close() error {
+	const op = "grpc.user.close"
+
+	if err := c.cc.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
 func (c *Client) Register(ctx context.Context, email string, login string, password string) (int64, error) {
 	const op = "grpc.user.register"
 	resp, err := c.api.Register(ctx, &user1.RegisterRequest{
@@ -2,6 +2,7 @@ package usergrpc
 
 import (
 	"context"
+	"ecomGateway/internal/grpc/interceptors"
 	"errors"
 	"log/slog"
 	"net"
@@ -70,7 +71,8 @@ func setupTestGRPCServer(t *testing.T, mockSrv *mockUserServer) (*Client, func()
 		slog.Default(),
 		"passthrough:///bufnet",
 		1*time.Second,
-		1,
+		interceptors.RetryPolicy{MaxRetries: 1},
+		interceptors.BreakerPolicy{},
 		grpc.WithContextDialer(bufDialer),
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
@@ -232,3 +234,22 @@ func TestClient_GetUser_EmptyDetails(t *testing.T) {
 	assert.Contains(t, err.Error(), "user details are empty")
 	assert.Contains(t, err.Error(), "grpc.user.getUser")
 }
+
+func TestClient_Close(t *testing.T) {
+	mockSrv := &mockUserServer{}
+	client, cleanup := setupTestGRPCServer(t, mockSrv)
+	defer cleanup()
+
+	mockSrv.LoginFunc = func(ctx context.Context, req *user1.LoginRequest) (*user1.LoginResponse, error) {
+		return &user1.LoginResponse{Token: "token"}, nil
+	}
+
+	_, err := client.Login(context.Background(), "login", "password")
+	require.NoError(t, err)
+
+	require.NoError(t, client.Close())
+
+	_, err = client.Login(context.Background(), "login", "password")
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	assert.Error(t, client.Close(), "closing twice must report an error")
+}
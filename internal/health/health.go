@@ -0,0 +1,187 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"ecomGateway/internal/grpc/interceptors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	statusUp       = "up"
+	statusDown     = "down"
+	statusReady    = "ready"
+	statusNotReady = "not_ready"
+)
+
+// Dependency is a backend the gateway needs in order to serve traffic.
+type Dependency struct {
+	Name string
+	Conn *grpc.ClientConn
+	// Required dependencies being down make the gateway not ready; optional
+	// ones are only reported.
+	Required bool
+	// Service is the name passed to grpc.health.v1.Health/Check. Empty asks
+	// about the server as a whole.
+	Service string
+	// Breaker, if set, has its state reported. An open breaker does not make
+	// the dependency down by itself: the probe checks the backend directly.
+	Breaker *interceptors.Breaker
+}
+
+type Checker struct {
+	log     *slog.Logger
+	deps    []Dependency
+	timeout time.Duration
+	useRPC  bool
+}
+
+type dependencyStatus struct {
+	Status   string `json:"status"`
+	State    string `json:"state"`
+	Required bool   `json:"required"`
+	Breaker  string `json:"breaker,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// NewChecker creates a checker for deps. Each readiness probe waits at most
+// timeout for a dependency; with useRPC it also calls the standard gRPC
+// health service on the backend.
+func NewChecker(log *slog.Logger, timeout time.Duration, useRPC bool, deps ...Dependency) *Checker {
+	return &Checker{
+		log:     log,
+		deps:    deps,
+		timeout: timeout,
+		useRPC:  useRPC,
+	}
+}
+
+// Liveness reports that the process is up and able to serve HTTP.
+func (c *Checker) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": statusUp})
+}
+
+// Readiness probes every dependency and answers 503 if a required one is down.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+	defer cancel()
+
+	resp := readinessResponse{
+		Status:       statusReady,
+		Dependencies: make(map[string]dependencyStatus, len(c.deps)),
+	}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, dep := range c.deps {
+		wg.Add(1)
+		go func(dep Dependency) {
+			defer wg.Done()
+
+			depStatus := c.check(ctx, dep)
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Dependencies[dep.Name] = depStatus
+			if depStatus.Status == statusDown && dep.Required {
+				resp.Status = statusNotReady
+			}
+		}(dep)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if resp.Status != statusReady {
+		code = http.StatusServiceUnavailable
+		c.log.Warn("Gateway is not ready", slog.Any("dependencies", resp.Dependencies))
+	}
+
+	writeJSON(w, code, resp)
+}
+
+func (c *Checker) check(ctx context.Context, dep Dependency) dependencyStatus {
+	depStatus := dependencyStatus{Status: statusUp, Required: dep.Required}
+	if dep.Breaker != nil {
+		depStatus.Breaker = dep.Breaker.State().String()
+	}
+
+	state, err := waitForReady(ctx, dep.Conn)
+	depStatus.State = state.String()
+	if err == nil && c.useRPC {
+		err = checkHealthRPC(ctx, dep)
+	}
+
+	if err != nil {
+		depStatus.Status = statusDown
+		depStatus.Error = err.Error()
+	}
+
+	return depStatus
+}
+
+// waitForReady kicks an idle connection and waits until it is READY, fails
+// or ctx expires.
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) (connectivity.State, error) {
+	state := conn.GetState()
+	for state != connectivity.Ready {
+		switch state {
+		case connectivity.Idle:
+			conn.Connect()
+		case connectivity.TransientFailure:
+			return state, errors.New("connection is in transient failure")
+		case connectivity.Shutdown:
+			return state, errors.New("connection is shut down")
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return state, fmt.Errorf("connection not ready: %w", ctx.Err())
+		}
+		state = conn.GetState()
+	}
+
+	return state, nil
+}
+
+func checkHealthRPC(ctx context.Context, dep Dependency) error {
+	resp, err := healthpb.NewHealthClient(dep.Conn).Check(ctx, &healthpb.HealthCheckRequest{Service: dep.Service})
+	if err != nil {
+		// A backend without the health service is reachable, which is all
+		// the connectivity check can tell anyway.
+		if status.Code(err) == codes.Unimplemented {
+			return nil
+		}
+		return fmt.Errorf("health check failed: %w", err)
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("health check reported %s", resp.GetStatus())
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(payload)
+}
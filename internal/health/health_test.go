@@ -0,0 +1,153 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/grpc/interceptors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func setupTestHealthServer(t *testing.T) (*grpc.ClientConn, *grpchealth.Server, func()) {
+	t.Helper()
+
+	bufSize := 1024 * 1024
+	lis := bufconn.Listen(bufSize)
+
+	grpcServer := grpc.NewServer()
+	healthSrv := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Logf("gRPC server error: %v", err)
+		}
+	}()
+
+	bufDialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		conn.Close()
+		grpcServer.Stop()
+		lis.Close()
+	}
+
+	return conn, healthSrv, cleanup
+}
+
+func probe(t *testing.T, checker *Checker) (int, readinessResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	checker.Readiness(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var resp readinessResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestLiveness(t *testing.T) {
+	checker := NewChecker(slog.Default(), time.Second, false)
+
+	rec := httptest.NewRecorder()
+	checker.Liveness(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"up"}`, rec.Body.String())
+}
+
+func TestReadiness_AllUp(t *testing.T) {
+	conn, _, cleanup := setupTestHealthServer(t)
+	defer cleanup()
+
+	breaker := interceptors.NewBreaker("product", interceptors.BreakerPolicy{FailureRatio: 0.5}, nil)
+
+	checker := NewChecker(slog.Default(), time.Second, true,
+		Dependency{Name: "product", Conn: conn, Required: true, Breaker: breaker},
+	)
+
+	code, resp := probe(t, checker)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, statusReady, resp.Status)
+	assert.Equal(t, statusUp, resp.Dependencies["product"].Status)
+	assert.Equal(t, "READY", resp.Dependencies["product"].State)
+	assert.Equal(t, "closed", resp.Dependencies["product"].Breaker)
+}
+
+func TestReadiness_RequiredNotServing(t *testing.T) {
+	conn, healthSrv, cleanup := setupTestHealthServer(t)
+	defer cleanup()
+
+	healthSrv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	checker := NewChecker(slog.Default(), time.Second, true,
+		Dependency{Name: "product", Conn: conn, Required: true},
+	)
+
+	code, resp := probe(t, checker)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusNotReady, resp.Status)
+	assert.Equal(t, statusDown, resp.Dependencies["product"].Status)
+	assert.Contains(t, resp.Dependencies["product"].Error, "NOT_SERVING")
+}
+
+func TestReadiness_OptionalDown(t *testing.T) {
+	conn, _, cleanup := setupTestHealthServer(t)
+	defer cleanup()
+
+	unreachable, err := grpc.NewClient(
+		"passthrough:///unreachable",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return nil, errors.New("connection refused")
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer unreachable.Close()
+
+	checker := NewChecker(slog.Default(), 500*time.Millisecond, false,
+		Dependency{Name: "user", Conn: conn, Required: true},
+		Dependency{Name: "order", Conn: unreachable, Required: false},
+	)
+
+	code, resp := probe(t, checker)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, statusUp, resp.Dependencies["user"].Status)
+	assert.Equal(t, statusDown, resp.Dependencies["order"].Status)
+	assert.False(t, resp.Dependencies["order"].Required)
+
+	checker = NewChecker(slog.Default(), 500*time.Millisecond, false,
+		Dependency{Name: "order", Conn: unreachable, Required: true},
+	)
+
+	code, resp = probe(t, checker)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, statusNotReady, resp.Status)
+}
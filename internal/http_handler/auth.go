@@ -0,0 +1,102 @@
+package httphandler
+
+import (
+	"context"
+	jwtmethod "ecomGateway/internal/lib/jwt_method"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UserIdentity is the authenticated caller extracted from the JWT.
+type UserIdentity struct {
+	UserID    int64
+	ExpiresAt time.Time
+	IssuedAt  time.Time
+}
+
+type contextKey int
+
+const userIdentityKey contextKey = iota
+
+func withUserIdentity(ctx context.Context, identity UserIdentity) context.Context {
+	return context.WithValue(ctx, userIdentityKey, identity)
+}
+
+// UserFromContext returns the identity stored by the authentication middleware.
+func UserFromContext(ctx context.Context) (UserIdentity, bool) {
+	identity, ok := ctx.Value(userIdentityKey).(UserIdentity)
+	return identity, ok
+}
+
+// authenticate validates the bearer token and puts the caller identity into
+// the request context. Requests without a valid token are rejected with 401.
+func (h *HTTPHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := bearerToken(r)
+		if err != nil {
+			h.log(r).Warn("Missing or malformed Authorization header", slog.String("error", err.Error()))
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+
+		payload, err := jwtmethod.ParseJWT(token, h.publicKey)
+		if err != nil {
+			h.log(r).Warn("Invalid access token", slog.String("error", err.Error()))
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			h.respondWithError(w, r, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		identity, err := identityFromPayload(payload)
+		if err != nil {
+			h.log(r).Warn("Invalid access token claims", slog.String("error", err.Error()))
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			h.respondWithError(w, r, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withUserIdentity(r.Context(), identity)))
+	})
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", errors.New("authorization header is empty")
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", errors.New("authorization scheme is not Bearer")
+	}
+
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", errors.New("bearer token is empty")
+	}
+
+	return token, nil
+}
+
+func identityFromPayload(payload map[string]interface{}) (UserIdentity, error) {
+	rawID, _ := payload["user_id"].(string)
+	userID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || userID <= 0 {
+		return UserIdentity{}, fmt.Errorf("invalid user_id claim %q", rawID)
+	}
+
+	exp, _ := payload["exp"].(time.Time)
+	iat, _ := payload["iat"].(time.Time)
+
+	return UserIdentity{
+		UserID:    userID,
+		ExpiresAt: exp,
+		IssuedAt:  iat,
+	}, nil
+}
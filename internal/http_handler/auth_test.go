@@ -0,0 +1,104 @@
+package httphandler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtmethod "ecomGateway/internal/lib/jwt_method"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signTestToken(t *testing.T, key *rsa.PrivateKey, userID string, exp time.Time) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwtmethod.CustomClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(exp),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	})
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func setupAuthTest(t *testing.T) (*rsa.PrivateKey, http.Handler, *UserIdentity) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	h := NewHTTPHandler(nil, slog.Default(), Options{PublicKey: &key.PublicKey})
+
+	var seen UserIdentity
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := UserFromContext(r.Context())
+		require.True(t, ok)
+		seen = identity
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return key, h.authenticate(next), &seen
+}
+
+func TestAuthenticate_ValidToken(t *testing.T) {
+	key, handler, seen := setupAuthTest(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signTestToken(t, key, "42", time.Now().Add(time.Hour)))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, int64(42), seen.UserID)
+	assert.False(t, seen.ExpiresAt.IsZero())
+}
+
+func TestAuthenticate_Rejected(t *testing.T) {
+	key, handler, _ := setupAuthTest(t)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{name: "missing header", header: ""},
+		{name: "wrong scheme", header: "Basic dXNlcjpwYXNz"},
+		{name: "expired token", header: "Bearer " + signTestToken(t, key, "42", time.Now().Add(-time.Minute))},
+		{name: "foreign key", header: "Bearer " + signTestToken(t, otherKey, "42", time.Now().Add(time.Hour))},
+		{name: "non numeric user id", header: "Bearer " + signTestToken(t, key, "abc", time.Now().Add(time.Hour))},
+		{name: "garbage", header: "Bearer not-a-jwt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+			var resp errorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.NotEmpty(t, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package httphandler
+
+import (
+	"ecomGateway/internal/processor"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type updateCartItemRequest struct {
+	Quantity int32 `json:"quantity"`
+}
+
+func (h *HTTPHandler) getCart(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	cart, err := h.processor.GetCart(r.Context(), identity.UserID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to get cart", slog.Int64("userID", identity.UserID))
+		return
+	}
+
+	h.respondWithCart(w, r, cart)
+}
+
+func (h *HTTPHandler) addCartItem(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	var req processor.OrderItemInput
+	if !h.readCartRequest(w, r, &req) {
+		return
+	}
+
+	cart, err := h.processor.AddCartItem(r.Context(), identity.UserID, req)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to add cart item", slog.Int64("userID", identity.UserID), slog.Int64("productID", req.ProductID))
+		return
+	}
+
+	h.respondWithCart(w, r, cart)
+}
+
+func (h *HTTPHandler) updateCartItem(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	productID, ok := h.cartProductIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateCartItemRequest
+	if !h.readCartRequest(w, r, &req) {
+		return
+	}
+
+	item := processor.OrderItemInput{ProductID: productID, Quantity: req.Quantity}
+	cart, err := h.processor.UpdateCartItem(r.Context(), identity.UserID, item)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to update cart item", slog.Int64("userID", identity.UserID), slog.Int64("productID", productID))
+		return
+	}
+
+	h.respondWithCart(w, r, cart)
+}
+
+func (h *HTTPHandler) removeCartItem(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	productID, ok := h.cartProductIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	cart, err := h.processor.RemoveCartItem(r.Context(), identity.UserID, productID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to remove cart item", slog.Int64("userID", identity.UserID), slog.Int64("productID", productID))
+		return
+	}
+
+	h.respondWithCart(w, r, cart)
+}
+
+func (h *HTTPHandler) clearCart(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	if err := h.processor.ClearCart(r.Context(), identity.UserID); err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to clear cart", slog.Int64("userID", identity.UserID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPHandler) checkoutCart(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	order, err := h.processor.CheckoutCart(r.Context(), identity.UserID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to check out cart", slog.Int64("userID", identity.UserID))
+		return
+	}
+
+	h.log(r).Info("Cart checked out successfully", slog.Int64("userID", identity.UserID), slog.Int64("orderID", order.ID))
+	h.respondWithJSON(w, r, http.StatusCreated, createOrderResponse{
+		OrderID:    order.ID,
+		TotalPrice: order.TotalPrice,
+		Message:    "Order created successfully",
+	})
+}
+
+func (h *HTTPHandler) respondWithCart(w http.ResponseWriter, r *http.Request, cart *processor.Cart) {
+	if cart.Partial {
+		h.log(r).Warn("Cart is missing product data",
+			slog.Int64("userID", cart.UserID),
+			slog.Any("missingProductIDs", cart.MissingProductIDs),
+		)
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, cart)
+}
+
+func (h *HTTPHandler) readCartRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.log(r).Error("Failed to read request body for cart", slog.String("error", err.Error()))
+		h.respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
+		return false
+	}
+	defer r.Body.Close()
+
+	if err := json.Unmarshal(body, req); err != nil {
+		h.log(r).Error("Failed to unmarshal cart request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid JSON payload")
+		return false
+	}
+	return true
+}
+
+func (h *HTTPHandler) cartProductIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := chi.URLParam(r, "id")
+	productID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || productID <= 0 {
+		h.log(r).Warn("Invalid product id", slog.String("id", idStr))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid product id")
+		return 0, false
+	}
+	return productID, true
+}
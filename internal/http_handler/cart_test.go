@@ -0,0 +1,136 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/idempotency"
+	"ecomGateway/internal/processor"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupCart serves the routes of the handler as user 5, skipping the token
+// check of authenticate.
+func setupCart(proc processor.Processor) http.Handler {
+	h := NewHTTPHandler(proc, slog.Default(), Options{Idempotency: idempotency.NewMemoryStore(time.Hour)})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(withUserIdentity(r.Context(), UserIdentity{UserID: 5})))
+		})
+	})
+	router.Get("/cart", h.getCart)
+	router.Delete("/cart", h.clearCart)
+	router.Post("/cart/items", h.addCartItem)
+	router.Put("/cart/items/{id}", h.updateCartItem)
+	router.Delete("/cart/items/{id}", h.removeCartItem)
+	router.With(h.idempotent).Post("/cart/checkout", h.checkoutCart)
+	return router
+}
+
+func serveCart(router http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(idempotencyKeyHeader, "checkout-1")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCart_Routes(t *testing.T) {
+	cart := &processor.Cart{
+		UserID:     5,
+		Items:      []processor.CartItem{{ProductID: 7, Quantity: 2, Name: "Laptop", Price: 100, Subtotal: 200, InStock: true}},
+		TotalPrice: 200,
+	}
+	var calls []string
+	proc := &stubProcessor{
+		GetCartFunc: func(ctx context.Context, userID int64) (*processor.Cart, error) {
+			calls = append(calls, fmt.Sprintf("get %d", userID))
+			return cart, nil
+		},
+		AddCartItemFunc: func(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error) {
+			calls = append(calls, fmt.Sprintf("add %d %d x%d", userID, item.ProductID, item.Quantity))
+			return cart, nil
+		},
+		UpdateCartItemFunc: func(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error) {
+			calls = append(calls, fmt.Sprintf("update %d %d x%d", userID, item.ProductID, item.Quantity))
+			return cart, nil
+		},
+		RemoveCartItemFunc: func(ctx context.Context, userID, productID int64) (*processor.Cart, error) {
+			calls = append(calls, fmt.Sprintf("remove %d %d", userID, productID))
+			return cart, nil
+		},
+		ClearCartFunc: func(ctx context.Context, userID int64) error {
+			calls = append(calls, fmt.Sprintf("clear %d", userID))
+			return nil
+		},
+		CheckoutCartFunc: func(ctx context.Context, userID int64) (*processor.Order, error) {
+			calls = append(calls, fmt.Sprintf("checkout %d", userID))
+			return &processor.Order{ID: 42, UserID: userID, TotalPrice: 200}, nil
+		},
+	}
+	router := setupCart(proc)
+
+	rec := serveCart(router, http.MethodGet, "/cart", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"user_id":5,"items":[{"product_id":7,"quantity":2,"name":"Laptop","price":100,"subtotal":200,"in_stock":true}],"total_price":200,"partial":false}`, rec.Body.String())
+
+	assert.Equal(t, http.StatusOK, serveCart(router, http.MethodPost, "/cart/items", `{"product_id":7,"quantity":2}`).Code)
+	assert.Equal(t, http.StatusOK, serveCart(router, http.MethodPut, "/cart/items/7", `{"quantity":3}`).Code)
+	assert.Equal(t, http.StatusOK, serveCart(router, http.MethodDelete, "/cart/items/7", "").Code)
+	assert.Equal(t, http.StatusNoContent, serveCart(router, http.MethodDelete, "/cart", "").Code)
+
+	rec = serveCart(router, http.MethodPost, "/cart/checkout", "")
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"order_id":42,"total_price":200,"message":"Order created successfully"}`, rec.Body.String())
+
+	rec = serveCart(router, http.MethodPost, "/cart/checkout", "")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"), "checkout honors Idempotency-Key")
+
+	assert.Equal(t, []string{"get 5", "add 5 7 x2", "update 5 7 x3", "remove 5 7", "clear 5", "checkout 5"}, calls)
+}
+
+func TestCart_Errors(t *testing.T) {
+	proc := &stubProcessor{
+		AddCartItemFunc: func(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error) {
+			return nil, processor.ErrCartFull
+		},
+		RemoveCartItemFunc: func(ctx context.Context, userID, productID int64) (*processor.Cart, error) {
+			return nil, processor.ErrCartItemNotFound
+		},
+		CheckoutCartFunc: func(ctx context.Context, userID int64) (*processor.Order, error) {
+			return nil, processor.ErrEmptyCart
+		},
+	}
+	router := setupCart(proc)
+
+	tests := []struct {
+		name, method, target, body string
+		status                     int
+		code                       string
+	}{
+		{"invalid json", http.MethodPost, "/cart/items", `{"product_id":`, http.StatusBadRequest, errCodeInvalidArgument},
+		{"invalid product id", http.MethodPut, "/cart/items/abc", `{"quantity":1}`, http.StatusBadRequest, errCodeInvalidArgument},
+		{"full cart", http.MethodPost, "/cart/items", `{"product_id":7,"quantity":1}`, http.StatusConflict, errCodeCartFull},
+		{"missing line", http.MethodDelete, "/cart/items/7", "", http.StatusNotFound, errCodeNotFound},
+		{"empty cart", http.MethodPost, "/cart/checkout", "", http.StatusBadRequest, errCodeInvalidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveCart(router, tt.method, tt.target, tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, rec.Body.String(), `"code":"`+tt.code+`"`)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package httphandler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// CacheControlOptions sets the Cache-Control header of the read routes; an
+// empty value leaves the header out.
+type CacheControlOptions struct {
+	// Products applies to GET /products and Product to GET /products/{id}.
+	Products string
+	Product  string
+	// Orders applies to GET /orders and Order to GET /orders/{id} and
+	// GET /orders/{id}/details.
+	Orders string
+	Order  string
+}
+
+// respondWithETag writes payload as JSON with a strong ETag computed from the
+// serialized payload, and answers 304 Not Modified without a body when the
+// client already holds that representation.
+func (h *HTTPHandler) respondWithETag(w http.ResponseWriter, r *http.Request, payload interface{}, cacheControl string) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		h.log(r).Error("Failed to marshal JSON response", slog.String("error", err.Error()))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"failed to marshal response"}`))
+		return
+	}
+
+	sum := sha256.Sum256(response)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	if etagMatches(r.Header.Values("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(response)
+}
+
+// etagMatches applies the weak comparison RFC 9110 prescribes for
+// If-None-Match to the listed entity tags.
+func etagMatches(ifNoneMatch []string, etag string) bool {
+	for _, header := range ifNoneMatch {
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
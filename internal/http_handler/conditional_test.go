@@ -0,0 +1,100 @@
+package httphandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ecomGateway/internal/processor"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProduct_ConditionalRequests(t *testing.T) {
+	product := &processor.Product{Id: 7, Name: "Laptop", Price: 120000, StockCount: 10}
+	proc := &stubProcessor{
+		GetProductFunc: func(ctx context.Context, id int64) (*processor.Product, error) {
+			p := *product
+			return &p, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{
+		CacheControl: CacheControlOptions{Product: "public, max-age=30"},
+	})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/products/7", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.NotContains(t, etag, "W/", "the ETag is strong")
+	assert.Equal(t, "public, max-age=30", first.Header().Get("Cache-Control"))
+	assert.Equal(t, etag, get("").Header().Get("ETag"), "the same payload gives the same ETag")
+
+	notModified := get(etag)
+	assert.Equal(t, http.StatusNotModified, notModified.Code)
+	assert.Empty(t, notModified.Body.String())
+	assert.Equal(t, etag, notModified.Header().Get("ETag"))
+	assert.Equal(t, "public, max-age=30", notModified.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusNotModified, get(`"other", W/`+etag).Code, "weak comparison over a list")
+	assert.Equal(t, http.StatusNotModified, get("*").Code)
+
+	product.StockCount = 9
+	changed := get(etag)
+	assert.Equal(t, http.StatusOK, changed.Code)
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+}
+
+func TestListOrders_ETag(t *testing.T) {
+	proc := &stubProcessor{
+		ListUserOrdersFunc: func(ctx context.Context, userID int64) ([]processor.Order, error) {
+			return []processor.Order{{ID: 1, UserID: userID, TotalPrice: 100}}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{
+		CacheControl: CacheControlOptions{Orders: "private, no-cache"},
+	})
+
+	rec := serveAs(h, UserIdentity{UserID: 5}, "/orders", h.listOrders, httptest.NewRequest(http.MethodGet, "/orders", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = serveAs(h, UserIdentity{UserID: 5}, "/orders", h.listOrders, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestGetProduct_NoCacheControlByDefault(t *testing.T) {
+	proc := &stubProcessor{
+		GetProductFunc: func(ctx context.Context, id int64) (*processor.Product, error) {
+			return &processor.Product{Id: id}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/7", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("ETag"))
+	assert.Empty(t, rec.Header().Get("Cache-Control"))
+}
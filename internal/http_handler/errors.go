@@ -0,0 +1,178 @@
+package httphandler
+
+import (
+	"context"
+	"ecomGateway/internal/processor"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Machine-readable error codes returned in errorResponse.Code. They are part
+// of the public API and must not change once published.
+const (
+	errCodeInvalidArgument    = "invalid_argument"
+	errCodeUnauthenticated    = "unauthenticated"
+	errCodePermissionDenied   = "permission_denied"
+	errCodeNotFound           = "not_found"
+	errCodeAlreadyExists      = "already_exists"
+	errCodeConflict           = "conflict"
+	errCodeFailedPrecondition = "failed_precondition"
+	errCodeInsufficientStock  = "insufficient_stock"
+	errCodeInvalidOrder       = "invalid_order"
+	errCodeCartFull           = "cart_full"
+	errCodeIdempotencyReuse   = "idempotency_key_reused"
+	errCodeRateLimited        = "rate_limited"
+	errCodeNotImplemented     = "not_implemented"
+	errCodeUnavailable        = "service_unavailable"
+	errCodeTimeout            = "timeout"
+	errCodeOutcomeUnknown     = "order_outcome_unknown"
+	errCodeInternal           = "internal_error"
+)
+
+type apiError struct {
+	Status int
+	Code   string
+}
+
+var grpcErrors = map[codes.Code]apiError{
+	codes.InvalidArgument:    {Status: http.StatusBadRequest, Code: errCodeInvalidArgument},
+	codes.OutOfRange:         {Status: http.StatusBadRequest, Code: errCodeInvalidArgument},
+	codes.Unauthenticated:    {Status: http.StatusUnauthorized, Code: errCodeUnauthenticated},
+	codes.PermissionDenied:   {Status: http.StatusForbidden, Code: errCodePermissionDenied},
+	codes.NotFound:           {Status: http.StatusNotFound, Code: errCodeNotFound},
+	codes.AlreadyExists:      {Status: http.StatusConflict, Code: errCodeAlreadyExists},
+	codes.Aborted:            {Status: http.StatusConflict, Code: errCodeConflict},
+	codes.FailedPrecondition: {Status: http.StatusConflict, Code: errCodeFailedPrecondition},
+	codes.ResourceExhausted:  {Status: http.StatusTooManyRequests, Code: errCodeRateLimited},
+	codes.Unimplemented:      {Status: http.StatusNotImplemented, Code: errCodeNotImplemented},
+	codes.Unavailable:        {Status: http.StatusServiceUnavailable, Code: errCodeUnavailable},
+	codes.DeadlineExceeded:   {Status: http.StatusGatewayTimeout, Code: errCodeTimeout},
+}
+
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:          errCodeInvalidArgument,
+	http.StatusUnauthorized:        errCodeUnauthenticated,
+	http.StatusForbidden:           errCodePermissionDenied,
+	http.StatusNotFound:            errCodeNotFound,
+	http.StatusConflict:            errCodeConflict,
+	http.StatusTooManyRequests:     errCodeRateLimited,
+	http.StatusNotImplemented:      errCodeNotImplemented,
+	http.StatusServiceUnavailable:  errCodeUnavailable,
+	http.StatusGatewayTimeout:      errCodeTimeout,
+	http.StatusInternalServerError: errCodeInternal,
+}
+
+// grpcStatus finds the status returned by a backend underneath the
+// "op: ..." wrapping added by the gRPC clients and the processor. Unlike
+// status.FromError it keeps the backend's original message.
+func grpcStatus(err error) (*status.Status, bool) {
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) || se.GRPCStatus() == nil {
+		return nil, false
+	}
+	return se.GRPCStatus(), true
+}
+
+// translateError maps a processor error to the HTTP status and error code
+// reported to the client.
+func translateError(err error) apiError {
+	switch {
+	case errors.Is(err, processor.ErrOrderOutcomeUnknown):
+		return apiError{Status: http.StatusGatewayTimeout, Code: errCodeOutcomeUnknown}
+	case errors.Is(err, processor.ErrEmptyOrder),
+		errors.Is(err, processor.ErrEmptyCart),
+		errors.Is(err, processor.ErrInvalidQuantity),
+		errors.Is(err, processor.ErrInvalidProductID):
+		return apiError{Status: http.StatusBadRequest, Code: errCodeInvalidOrder}
+	case errors.Is(err, processor.ErrInsufficientStock):
+		return apiError{Status: http.StatusConflict, Code: errCodeInsufficientStock}
+	case errors.Is(err, processor.ErrOrderNotFound):
+		return apiError{Status: http.StatusNotFound, Code: errCodeNotFound}
+	case errors.Is(err, processor.ErrInvalidProductQuery):
+		return apiError{Status: http.StatusBadRequest, Code: errCodeInvalidArgument}
+	case errors.Is(err, processor.ErrCartItemNotFound):
+		return apiError{Status: http.StatusNotFound, Code: errCodeNotFound}
+	case errors.Is(err, processor.ErrCartFull):
+		return apiError{Status: http.StatusConflict, Code: errCodeCartFull}
+	case errors.Is(err, processor.ErrCheckoutInProgress):
+		return apiError{Status: http.StatusConflict, Code: errCodeConflict}
+	}
+
+	if st, ok := grpcStatus(err); ok {
+		if apiErr, ok := grpcErrors[st.Code()]; ok {
+			return apiErr
+		}
+		return apiError{Status: http.StatusInternalServerError, Code: errCodeInternal}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return apiError{Status: http.StatusGatewayTimeout, Code: errCodeTimeout}
+	}
+
+	return apiError{Status: http.StatusInternalServerError, Code: errCodeInternal}
+}
+
+// errorMessage picks the message shown to the client. Server-side failures
+// get the generic fallback so backend internals are not leaked.
+func errorMessage(err error, apiErr apiError, fallback string) string {
+	if apiErr.Status >= http.StatusInternalServerError {
+		return fallback
+	}
+
+	var orderErr *processor.OrderError
+	if errors.As(err, &orderErr) {
+		err = orderErr.Err
+	}
+
+	if st, ok := grpcStatus(err); ok {
+		if msg := st.Message(); msg != "" {
+			return msg
+		}
+		return fallback
+	}
+
+	return err.Error()
+}
+
+// respondWithProcessorError translates err, logs it with the given message and
+// attributes and writes the error response.
+func (h *HTTPHandler) respondWithProcessorError(w http.ResponseWriter, r *http.Request, err error, message string, attrs ...any) {
+	apiErr := translateError(err)
+
+	attrs = append(attrs, slog.String("error", err.Error()), slog.Int("status", apiErr.Status))
+	if apiErr.Status >= http.StatusInternalServerError {
+		h.log(r).Error(message, attrs...)
+	} else {
+		h.log(r).Warn(message, attrs...)
+	}
+
+	resp := errorResponse{
+		Error: errorMessage(err, apiErr, message),
+		Code:  apiErr.Code,
+	}
+
+	var orderErr *processor.OrderError
+	if errors.As(err, &orderErr) {
+		switch {
+		case orderErr.Compensated:
+			resp.Compensation = &compensationResponse{Status: "stock_released"}
+		case errors.Is(err, processor.ErrOrderOutcomeUnknown):
+			resp.Compensation = &compensationResponse{
+				Status:              "stock_held",
+				UnsettledProductIDs: orderErr.UnsettledIDs,
+			}
+		default:
+			resp.Compensation = &compensationResponse{
+				Status:              "stock_release_failed",
+				FailedProductIDs:    orderErr.FailedReleaseIDs,
+				UnsettledProductIDs: orderErr.UnsettledIDs,
+			}
+		}
+	}
+
+	h.respondWithJSON(w, r, apiErr.Status, resp)
+}
@@ -0,0 +1,104 @@
+package httphandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	requestid "ecomGateway/internal/lib/request_id"
+	"ecomGateway/internal/processor"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// wrapLikeClients reproduces the wrapping added by the gRPC clients and the
+// processor on top of a backend status.
+func wrapLikeClients(code codes.Code, msg string) error {
+	err := fmt.Errorf("grpc.product.get_product: %w", status.Error(code, msg))
+	return fmt.Errorf("product service error: %w", err)
+}
+
+func TestTranslateError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"already exists", wrapLikeClients(codes.AlreadyExists, "x"), http.StatusConflict, errCodeAlreadyExists},
+		{"invalid argument", wrapLikeClients(codes.InvalidArgument, "x"), http.StatusBadRequest, errCodeInvalidArgument},
+		{"not found", wrapLikeClients(codes.NotFound, "x"), http.StatusNotFound, errCodeNotFound},
+		{"unauthenticated", wrapLikeClients(codes.Unauthenticated, "x"), http.StatusUnauthorized, errCodeUnauthenticated},
+		{"permission denied", wrapLikeClients(codes.PermissionDenied, "x"), http.StatusForbidden, errCodePermissionDenied},
+		{"unavailable", wrapLikeClients(codes.Unavailable, "x"), http.StatusServiceUnavailable, errCodeUnavailable},
+		{"deadline exceeded", wrapLikeClients(codes.DeadlineExceeded, "x"), http.StatusGatewayTimeout, errCodeTimeout},
+		{"internal", wrapLikeClients(codes.Internal, "x"), http.StatusInternalServerError, errCodeInternal},
+		{"insufficient stock", fmt.Errorf("%w: product 1", processor.ErrInsufficientStock), http.StatusConflict, errCodeInsufficientStock},
+		{"empty order", processor.ErrEmptyOrder, http.StatusBadRequest, errCodeInvalidOrder},
+		{"foreign order", processor.ErrOrderNotFound, http.StatusNotFound, errCodeNotFound},
+		{"context deadline", fmt.Errorf("op: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, errCodeTimeout},
+		{"plain error", errors.New("boom"), http.StatusInternalServerError, errCodeInternal},
+		{
+			"order outcome unknown",
+			&processor.OrderError{Err: fmt.Errorf("%w: %w", processor.ErrOrderOutcomeUnknown, wrapLikeClients(codes.Unavailable, "x"))},
+			http.StatusGatewayTimeout, errCodeOutcomeUnknown,
+		},
+		{
+			"order error keeps cause",
+			&processor.OrderError{Err: wrapLikeClients(codes.Unavailable, "x"), Compensated: true},
+			http.StatusServiceUnavailable, errCodeUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := translateError(tt.err)
+			assert.Equal(t, tt.wantStatus, apiErr.Status)
+			assert.Equal(t, tt.wantCode, apiErr.Code)
+		})
+	}
+}
+
+func TestRespondWithProcessorError_Message(t *testing.T) {
+	h := NewHTTPHandler(nil, slog.Default(), Options{})
+
+	rec := httptest.NewRecorder()
+	h.respondWithProcessorError(rec, httptest.NewRequest(http.MethodGet, "/products/1", nil), wrapLikeClients(codes.NotFound, "product not found"), "Failed to get product")
+
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "product not found", resp.Error, "op prefixes must not leak into the message")
+	assert.Equal(t, errCodeNotFound, resp.Code)
+
+	rec = httptest.NewRecorder()
+	h.respondWithProcessorError(rec, httptest.NewRequest(http.MethodGet, "/products/1", nil), wrapLikeClients(codes.Internal, "pq: connection refused"), "Failed to get product")
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Failed to get product", resp.Error)
+	assert.Equal(t, errCodeInternal, resp.Code)
+}
+
+func TestRespondWithProcessorError_LogsRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	h := NewHTTPHandler(nil, slog.New(slog.NewJSONHandler(&logs, nil)), Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/products/1", nil)
+	req = req.WithContext(requestid.NewContext(req.Context(), "req-123"))
+
+	h.respondWithProcessorError(httptest.NewRecorder(), req, wrapLikeClients(codes.Unavailable, "x"), "Failed to get product")
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "req-123", entry["request_id"])
+}
@@ -1,32 +1,110 @@
 package httphandler
 
 import (
+	"crypto/rsa"
+	"ecomGateway/internal/idempotency"
+	requestid "ecomGateway/internal/lib/request_id"
 	"ecomGateway/internal/processor"
+	"ecomGateway/internal/ratelimit"
 	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 )
 
 type HTTPHandler struct {
-	processor processor.Processor
-	logger    *slog.Logger
+	processor    processor.Processor
+	logger       *slog.Logger
+	publicKey    *rsa.PublicKey
+	adminIDs     map[int64]struct{}
+	clientIP     *ratelimit.IPResolver
+	limiters     rateLimiters
+	lockout      loginLockout
+	idempotency  idempotency.Store
+	cacheControl CacheControlOptions
 }
 
-func NewHTTPHandler(processor processor.Processor, logger *slog.Logger) *HTTPHandler {
+// Options holds the handler settings that come from the gateway config.
+type Options struct {
+	// PublicKey verifies the access tokens issued by the user service.
+	PublicKey *rsa.PublicKey
+	// AdminUserIDs may read any user's profile.
+	AdminUserIDs []int64
+	// ClientIP resolves the client address used by rate limiting and login
+	// lockout; by default X-Forwarded-For is ignored.
+	ClientIP  *ratelimit.IPResolver
+	RateLimit RateLimitOptions
+	Lockout   LockoutOptions
+	// Idempotency stores the responses replayed for Idempotency-Key
+	// duplicates on POST /register, POST /orders and POST /cart/checkout;
+	// nil disables it.
+	Idempotency  idempotency.Store
+	CacheControl CacheControlOptions
+}
+
+func NewHTTPHandler(processor processor.Processor, logger *slog.Logger, opts Options) *HTTPHandler {
+	adminIDs := make(map[int64]struct{}, len(opts.AdminUserIDs))
+	for _, id := range opts.AdminUserIDs {
+		adminIDs[id] = struct{}{}
+	}
+
+	clientIP := opts.ClientIP
+	if clientIP == nil {
+		clientIP = &ratelimit.IPResolver{}
+	}
+
 	return &HTTPHandler{
-		processor: processor,
-		logger:    logger,
+		processor:    processor,
+		logger:       logger,
+		publicKey:    opts.PublicKey,
+		adminIDs:     adminIDs,
+		clientIP:     clientIP,
+		limiters:     newRateLimiters(opts.RateLimit),
+		lockout:      newLoginLockout(opts.Lockout),
+		idempotency:  opts.Idempotency,
+		cacheControl: opts.CacheControl,
+	}
+}
+
+// log returns the handler logger annotated with the id of request r.
+func (h *HTTPHandler) log(r *http.Request) *slog.Logger {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		return h.logger.With(slog.String("request_id", id))
 	}
+	return h.logger
 }
 
 func (h *HTTPHandler) RegisterRoutes(router *chi.Mux) {
-	// Публичные роуты
-	router.Post("/register", h.register)
-	router.Post("/login", h.login)
+	router.Group(func(router chi.Router) {
+		router.Use(h.limitByIP)
+
+		// Публичные роуты
+		router.With(h.limitAuth, h.idempotent).Post("/register", h.register)
+		router.With(h.limitAuth).Post("/login", h.login)
+		router.Get("/products", h.listProducts)
+		router.Get("/products/{id}", h.getProduct)
 
+		// Защищённые роуты
+		router.Group(func(r chi.Router) {
+			r.Use(h.authenticate)
+			r.Use(h.limitByUser)
+			r.Get("/me", h.getMe)
+			r.Get("/users/{id}", h.getUser)
+			r.With(h.idempotent).Post("/orders", h.createOrder)
+			r.Get("/orders", h.listOrders)
+			r.Get("/orders/{id}", h.getOrder)
+			r.Get("/orders/{id}/details", h.getOrderView)
+			r.Get("/cart", h.getCart)
+			r.Delete("/cart", h.clearCart)
+			r.Post("/cart/items", h.addCartItem)
+			r.Put("/cart/items/{id}", h.updateCartItem)
+			r.Delete("/cart/items/{id}", h.removeCartItem)
+			r.With(h.idempotent).Post("/cart/checkout", h.checkoutCart)
+		})
+	})
 }
 
 type registerRequest struct {
@@ -50,44 +128,61 @@ type loginResponse struct {
 	Message string `json:"message"`
 }
 
+type createOrderRequest struct {
+	Items []processor.OrderItemInput `json:"items"`
+}
+
+type createOrderResponse struct {
+	OrderID    int64  `json:"order_id"`
+	TotalPrice int64  `json:"total_price"`
+	Message    string `json:"message"`
+}
+
 type errorResponse struct {
-	Error string `json:"error"`
+	Error        string                `json:"error"`
+	Code         string                `json:"code"`
+	Compensation *compensationResponse `json:"compensation,omitempty"`
+}
+
+type compensationResponse struct {
+	Status              string  `json:"status"`
+	FailedProductIDs    []int64 `json:"failed_product_ids,omitempty"`
+	UnsettledProductIDs []int64 `json:"unsettled_product_ids,omitempty"`
 }
 
 func (h *HTTPHandler) register(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Error("Failed to read request body", slog.String("error", err.Error()))
-		h.respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		h.log(r).Error("Failed to read request body", slog.String("error", err.Error()))
+		h.respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
 
 	var req registerRequest
 	if err := json.Unmarshal(body, &req); err != nil {
-		h.logger.Error("Failed to unmarshal request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
-		h.respondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		h.log(r).Error("Failed to unmarshal request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
 	if req.Email == "" || req.Password == "" || req.Login == "" {
-		h.logger.Warn("Missing required fields for registration",
+		h.log(r).Warn("Missing required fields for registration",
 			slog.String("email", req.Email),
 			slog.String("login", req.Login),
 		)
-		h.respondWithError(w, http.StatusBadRequest, "Email, password, and login are required")
+		h.respondWithError(w, r, http.StatusBadRequest, "Email, password, and login are required")
 		return
 	}
 
 	userID, err := h.processor.RegisterUser(r.Context(), req.Email, req.Password, req.Login)
 	if err != nil {
-		h.logger.Error("Processor failed to register user", slog.String("error", err.Error()))
-		h.respondWithError(w, http.StatusInternalServerError, "Failed to register user")
+		h.respondWithProcessorError(w, r, err, "Failed to register user", slog.String("login", req.Login))
 		return
 	}
 
-	h.logger.Info("User registered successfully", slog.Int64("userID", userID))
-	h.respondWithJSON(w, http.StatusCreated, registerResponse{
+	h.log(r).Info("User registered successfully", slog.Int64("userID", userID))
+	h.respondWithJSON(w, r, http.StatusCreated, registerResponse{
 		UserID:  userID,
 		Message: "User registered successfully",
 	})
@@ -96,43 +191,262 @@ func (h *HTTPHandler) register(w http.ResponseWriter, r *http.Request) {
 func (h *HTTPHandler) login(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		h.logger.Error("Failed to read request body for login", slog.String("error", err.Error()))
-		h.respondWithError(w, http.StatusBadRequest, "Failed to read request body")
+		h.log(r).Error("Failed to read request body for login", slog.String("error", err.Error()))
+		h.respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
 
 	var req loginRequest
 	if err := json.Unmarshal(body, &req); err != nil {
-		h.logger.Error("Failed to unmarshal login request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
-		h.respondWithError(w, http.StatusBadRequest, "Invalid JSON payload")
+		h.log(r).Error("Failed to unmarshal login request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
 	if req.Login == "" || req.Password == "" {
-		h.logger.Warn("Missing required fields for login", slog.String("login", req.Login))
-		h.respondWithError(w, http.StatusBadRequest, "Login and password are required")
+		h.log(r).Warn("Missing required fields for login", slog.String("login", req.Login))
+		h.respondWithError(w, r, http.StatusBadRequest, "Login and password are required")
+		return
+	}
+
+	ip := h.clientIP.ClientIP(r)
+
+	// A locked out attempt gets the same delay and response as a failed one
+	// so that lockouts cannot be told apart from bad credentials.
+	if h.lockout.locked(req.Login, ip) {
+		h.log(r).Warn("Login attempt while locked out", slog.String("login", req.Login), slog.String("ip", ip))
+		sleep(r.Context(), h.lockout.delay(req.Login, ip))
+		h.respondWithError(w, r, http.StatusUnauthorized, "Login failed. Check credentials.")
 		return
 	}
 
 	token, err := h.processor.LoginUser(r.Context(), req.Login, req.Password)
 	if err != nil {
-		h.logger.Error("Processor failed to login user", slog.String("login", req.Login), slog.String("error", err.Error()))
-		h.respondWithError(w, http.StatusUnauthorized, "Login failed. Check credentials.")
+		// Any client-side failure is reported as bad credentials so the
+		// response does not reveal whether the login exists.
+		if translateError(err).Status < http.StatusInternalServerError {
+			h.log(r).Warn("Processor failed to login user", slog.String("login", req.Login), slog.String("error", err.Error()))
+			sleep(r.Context(), h.lockout.fail(req.Login, ip))
+			h.respondWithError(w, r, http.StatusUnauthorized, "Login failed. Check credentials.")
+			return
+		}
+		h.respondWithProcessorError(w, r, err, "Login failed", slog.String("login", req.Login))
 		return
 	}
 
-	h.logger.Info("User logged in successfully", slog.String("login", req.Login))
-	h.respondWithJSON(w, http.StatusOK, loginResponse{
+	h.lockout.reset(req.Login)
+	h.log(r).Info("User logged in successfully", slog.String("login", req.Login))
+	h.respondWithJSON(w, r, http.StatusOK, loginResponse{
 		Token:   token,
 		Message: "Login successful",
 	})
 }
 
-func (h *HTTPHandler) respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+func (h *HTTPHandler) getMe(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	h.respondWithUser(w, r, identity.UserID)
+}
+
+func (h *HTTPHandler) getUser(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	userID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || userID <= 0 {
+		h.log(r).Warn("Invalid user id", slog.String("id", idStr))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid user id")
+		return
+	}
+
+	if userID != identity.UserID && !h.isAdmin(identity.UserID) {
+		h.log(r).Warn("User tried to read another profile",
+			slog.Int64("userID", identity.UserID),
+			slog.Int64("requestedUserID", userID),
+		)
+		h.respondWithError(w, r, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	h.respondWithUser(w, r, userID)
+}
+
+func (h *HTTPHandler) respondWithUser(w http.ResponseWriter, r *http.Request, userID int64) {
+	user, err := h.processor.GetUser(r.Context(), userID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to get user", slog.Int64("userID", userID))
+		return
+	}
+
+	h.respondWithJSON(w, r, http.StatusOK, user)
+}
+
+func (h *HTTPHandler) isAdmin(userID int64) bool {
+	_, ok := h.adminIDs[userID]
+	return ok
+}
+
+func (h *HTTPHandler) listProducts(w http.ResponseWriter, r *http.Request) {
+	query, err := parseProductQuery(r.URL.Query())
+	if err != nil {
+		h.log(r).Warn("Invalid product query", slog.String("query", r.URL.RawQuery), slog.String("error", err.Error()))
+		h.respondWithError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	page, err := h.processor.ListProductsPage(r.Context(), query)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to list products", slog.String("filter", query.Filter))
+		return
+	}
+
+	setPageLinks(w, r, page)
+	h.respondWithETag(w, r, page.Products, h.cacheControl.Products)
+}
+
+func (h *HTTPHandler) getProduct(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || id <= 0 {
+		h.log(r).Warn("Invalid product id", slog.String("id", idStr))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid product id")
+		return
+	}
+
+	product, err := h.processor.GetProduct(r.Context(), id)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to get product", slog.Int64("productID", id))
+		return
+	}
+
+	h.respondWithETag(w, r, product, h.cacheControl.Product)
+}
+
+func (h *HTTPHandler) createOrder(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.log(r).Error("Failed to read request body for order", slog.String("error", err.Error()))
+		h.respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	var req createOrderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		h.log(r).Error("Failed to unmarshal order request JSON", slog.String("error", err.Error()), slog.String("body", string(body)))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	order, err := h.processor.CreateOrder(r.Context(), identity.UserID, req.Items)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to create order", slog.Int64("userID", identity.UserID))
+		return
+	}
+
+	h.log(r).Info("Order created successfully", slog.Int64("userID", identity.UserID), slog.Int64("orderID", order.ID))
+	h.respondWithJSON(w, r, http.StatusCreated, createOrderResponse{
+		OrderID:    order.ID,
+		TotalPrice: order.TotalPrice,
+		Message:    "Order created successfully",
+	})
+}
+
+func (h *HTTPHandler) listOrders(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	orders, err := h.processor.ListUserOrders(r.Context(), identity.UserID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to list orders", slog.Int64("userID", identity.UserID))
+		return
+	}
+
+	h.respondWithETag(w, r, orders, h.cacheControl.Orders)
+}
+
+func (h *HTTPHandler) getOrder(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	orderID, ok := h.orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	order, err := h.processor.GetOrder(r.Context(), identity.UserID, orderID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to get order", slog.Int64("userID", identity.UserID), slog.Int64("orderID", orderID))
+		return
+	}
+
+	h.respondWithETag(w, r, order, h.cacheControl.Order)
+}
+
+func (h *HTTPHandler) getOrderView(w http.ResponseWriter, r *http.Request) {
+	identity, ok := UserFromContext(r.Context())
+	if !ok {
+		h.respondWithError(w, r, http.StatusUnauthorized, "Authentication required")
+		return
+	}
+
+	orderID, ok := h.orderIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	view, err := h.processor.GetOrderView(r.Context(), identity.UserID, orderID)
+	if err != nil {
+		h.respondWithProcessorError(w, r, err, "Failed to get order", slog.Int64("userID", identity.UserID), slog.Int64("orderID", orderID))
+		return
+	}
+
+	if view.Partial {
+		h.log(r).Warn("Order view is missing product data",
+			slog.Int64("orderID", orderID),
+			slog.Any("missingProductIDs", view.MissingProductIDs),
+		)
+	}
+
+	h.respondWithETag(w, r, view, h.cacheControl.Order)
+}
+
+func (h *HTTPHandler) orderIDParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	idStr := chi.URLParam(r, "id")
+	orderID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil || orderID <= 0 {
+		h.log(r).Warn("Invalid order id", slog.String("id", idStr))
+		h.respondWithError(w, r, http.StatusBadRequest, "Invalid order id")
+		return 0, false
+	}
+	return orderID, true
+}
+
+func (h *HTTPHandler) respondWithJSON(w http.ResponseWriter, r *http.Request, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
-		h.logger.Error("Failed to marshal JSON response", slog.String("error", err.Error()))
+		h.log(r).Error("Failed to marshal JSON response", slog.String("error", err.Error()))
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(`{"error":"failed to marshal response"}`))
 		return
@@ -142,6 +456,10 @@ func (h *HTTPHandler) respondWithJSON(w http.ResponseWriter, code int, payload i
 	w.Write(response)
 }
 
-func (h *HTTPHandler) respondWithError(w http.ResponseWriter, code int, message string) {
-	h.respondWithJSON(w, code, errorResponse{Error: message})
+func (h *HTTPHandler) respondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	errCode, ok := statusErrorCodes[code]
+	if !ok {
+		errCode = errCodeInternal
+	}
+	h.respondWithJSON(w, r, code, errorResponse{Error: message, Code: errCode})
 }
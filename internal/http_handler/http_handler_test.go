@@ -0,0 +1,294 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecomGateway/internal/processor"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubProcessor implements only the processor methods a test needs; calling
+// anything else panics on the nil embedded interface.
+type stubProcessor struct {
+	processor.Processor
+
+	RegisterUserFunc     func(ctx context.Context, email, password, login string) (int64, error)
+	GetUserFunc          func(ctx context.Context, userID int64) (*processor.User, error)
+	LoginUserFunc        func(ctx context.Context, login, password string) (string, error)
+	GetProductFunc       func(ctx context.Context, id int64) (*processor.Product, error)
+	ListProductsPageFunc func(ctx context.Context, query processor.ProductQuery) (*processor.ProductPage, error)
+	CreateOrderFunc      func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error)
+	ListUserOrdersFunc   func(ctx context.Context, userID int64) ([]processor.Order, error)
+	GetCartFunc          func(ctx context.Context, userID int64) (*processor.Cart, error)
+	AddCartItemFunc      func(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error)
+	UpdateCartItemFunc   func(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error)
+	RemoveCartItemFunc   func(ctx context.Context, userID, productID int64) (*processor.Cart, error)
+	ClearCartFunc        func(ctx context.Context, userID int64) error
+	CheckoutCartFunc     func(ctx context.Context, userID int64) (*processor.Order, error)
+}
+
+func (s *stubProcessor) RegisterUser(ctx context.Context, email, password, login string) (int64, error) {
+	return s.RegisterUserFunc(ctx, email, password, login)
+}
+
+func (s *stubProcessor) GetUser(ctx context.Context, userID int64) (*processor.User, error) {
+	return s.GetUserFunc(ctx, userID)
+}
+
+func (s *stubProcessor) LoginUser(ctx context.Context, login, password string) (string, error) {
+	return s.LoginUserFunc(ctx, login, password)
+}
+
+func (s *stubProcessor) GetProduct(ctx context.Context, id int64) (*processor.Product, error) {
+	return s.GetProductFunc(ctx, id)
+}
+
+func (s *stubProcessor) ListProductsPage(ctx context.Context, query processor.ProductQuery) (*processor.ProductPage, error) {
+	return s.ListProductsPageFunc(ctx, query)
+}
+
+func (s *stubProcessor) ListUserOrders(ctx context.Context, userID int64) ([]processor.Order, error) {
+	return s.ListUserOrdersFunc(ctx, userID)
+}
+
+func (s *stubProcessor) CreateOrder(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+	return s.CreateOrderFunc(ctx, userID, items)
+}
+
+func (s *stubProcessor) GetCart(ctx context.Context, userID int64) (*processor.Cart, error) {
+	return s.GetCartFunc(ctx, userID)
+}
+
+func (s *stubProcessor) AddCartItem(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error) {
+	return s.AddCartItemFunc(ctx, userID, item)
+}
+
+func (s *stubProcessor) UpdateCartItem(ctx context.Context, userID int64, item processor.OrderItemInput) (*processor.Cart, error) {
+	return s.UpdateCartItemFunc(ctx, userID, item)
+}
+
+func (s *stubProcessor) RemoveCartItem(ctx context.Context, userID, productID int64) (*processor.Cart, error) {
+	return s.RemoveCartItemFunc(ctx, userID, productID)
+}
+
+func (s *stubProcessor) ClearCart(ctx context.Context, userID int64) error {
+	return s.ClearCartFunc(ctx, userID)
+}
+
+func (s *stubProcessor) CheckoutCart(ctx context.Context, userID int64) (*processor.Order, error) {
+	return s.CheckoutCartFunc(ctx, userID)
+}
+
+// serveAs routes the request through a router without the auth middleware,
+// injecting identity directly the way authenticate would.
+func serveAs(h *HTTPHandler, identity UserIdentity, pattern string, handler http.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Get(pattern, handler)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req.WithContext(withUserIdentity(req.Context(), identity)))
+	return rec
+}
+
+func TestGetUser_Access(t *testing.T) {
+	proc := &stubProcessor{
+		GetUserFunc: func(ctx context.Context, userID int64) (*processor.User, error) {
+			if userID == 404 {
+				return nil, status.Error(codes.NotFound, "user not found")
+			}
+			return &processor.User{ID: userID, Login: "login", Email: "user@example.com"}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{AdminUserIDs: []int64{1}})
+
+	tests := []struct {
+		name     string
+		callerID int64
+		path     string
+		wantCode int
+	}{
+		{name: "own profile", callerID: 7, path: "/users/7", wantCode: http.StatusOK},
+		{name: "someone else", callerID: 7, path: "/users/8", wantCode: http.StatusForbidden},
+		{name: "admin reads anyone", callerID: 1, path: "/users/8", wantCode: http.StatusOK},
+		{name: "admin unknown user", callerID: 1, path: "/users/404", wantCode: http.StatusNotFound},
+		{name: "invalid id", callerID: 1, path: "/users/abc", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rec := serveAs(h, UserIdentity{UserID: tt.callerID}, "/users/{id}", h.getUser, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestGetMe(t *testing.T) {
+	proc := &stubProcessor{
+		GetUserFunc: func(ctx context.Context, userID int64) (*processor.User, error) {
+			return &processor.User{ID: userID, Login: "me", Email: "me@example.com"}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	rec := serveAs(h, UserIdentity{UserID: 5}, "/me", h.getMe, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var user processor.User
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &user))
+	assert.Equal(t, int64(5), user.ID)
+	assert.Equal(t, "me", user.Login)
+	assert.Equal(t, "me@example.com", user.Email)
+}
+
+func TestGetProduct(t *testing.T) {
+	proc := &stubProcessor{
+		GetProductFunc: func(ctx context.Context, id int64) (*processor.Product, error) {
+			switch id {
+			case 404:
+				return nil, status.Error(codes.NotFound, "product not found")
+			case 500:
+				return nil, status.Error(codes.Internal, "database is down")
+			}
+			return &processor.Product{Id: id, Name: "Laptop", Description: "15 inch", Price: 120000, StockCount: 3}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "found",
+			path:     "/products/7",
+			wantCode: http.StatusOK,
+			wantBody: `{"id":7,"name":"Laptop","description":"15 inch","price":120000,"stock_count":3}`,
+		},
+		{name: "unknown product", path: "/products/404", wantCode: http.StatusNotFound},
+		{name: "backend failure", path: "/products/500", wantCode: http.StatusInternalServerError},
+		{name: "invalid id", path: "/products/abc", wantCode: http.StatusBadRequest},
+		{name: "non-positive id", path: "/products/0", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestListProducts_Filter(t *testing.T) {
+	var filter string
+	proc := &stubProcessor{
+		ListProductsPageFunc: func(ctx context.Context, query processor.ProductQuery) (*processor.ProductPage, error) {
+			filter = query.Filter
+			return &processor.ProductPage{Products: []processor.Product{}}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products?filter=laptop", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[]`, rec.Body.String(), "an empty catalog is an empty array, not null")
+	assert.Equal(t, "laptop", filter)
+}
+
+func TestCreateOrder(t *testing.T) {
+	var gotUser int64
+	var gotItems []processor.OrderItemInput
+	proc := &stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			gotUser, gotItems = userID, items
+			switch {
+			case len(items) == 0:
+				return nil, processor.ErrEmptyOrder
+			case items[0].ProductID == 409:
+				return nil, fmt.Errorf("%w: product 409", processor.ErrInsufficientStock)
+			}
+			return &processor.Order{ID: 42, UserID: userID, TotalPrice: 240000}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "created",
+			body:     `{"items":[{"product_id":7,"quantity":2,"price":1}]}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"order_id":42,"total_price":240000,"message":"Order created successfully"}`,
+		},
+		{name: "empty order", body: `{"items":[]}`, wantCode: http.StatusBadRequest},
+		{name: "insufficient stock", body: `{"items":[{"product_id":409,"quantity":1}]}`, wantCode: http.StatusConflict},
+		{name: "invalid json", body: `{"items":`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			router.Post("/orders", h.createOrder)
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req.WithContext(withUserIdentity(req.Context(), UserIdentity{UserID: 5})))
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	assert.Equal(t, int64(5), gotUser, "orders are placed for the authenticated user")
+	require.Len(t, gotItems, 1)
+	assert.Equal(t, processor.OrderItemInput{ProductID: 409, Quantity: 1}, gotItems[0])
+}
+
+func TestCreateOrder_RequiresAuthentication(t *testing.T) {
+	proc := &stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			t.Fatal("the processor must not be reached without a token")
+			return nil, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{"items":[{"product_id":7,"quantity":1}]}`)))
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,158 @@
+package httphandler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"ecomGateway/internal/idempotency"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	idempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+// replayedHeaders are the response headers stored with an idempotent
+// response; the rest, such as X-Request-ID, belong to the original request.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// idempotent makes a mutating route safe to retry with an Idempotency-Key
+// header. The first response for a key is stored and replayed for duplicates;
+// a duplicate of an in-flight request gets 409 and reusing the key for a
+// different request gets 422. Keys are scoped per user, so on protected
+// routes this must run after authenticate. Anonymous keys are scoped by the
+// request fingerprint rather than the client IP, so a retry from a new
+// address is still replayed while unrelated clients only share a key if they
+// also send the same body. The scoped key is passed on in the request context
+// for the backend calls. Server errors are not stored so that the client may
+// retry them, unless the handler marked the request with idempotency.Keep
+// because it may have taken effect. Store failures let the request through
+// like the rate limiter does.
+func (h *HTTPHandler) idempotent(next http.Handler) http.Handler {
+	if h.idempotency == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			h.respondWithError(w, r, http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			h.log(r).Error("Failed to read request body", slog.String("error", err.Error()))
+			h.respondWithError(w, r, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+		scope := "anonymous:" + fingerprint
+		if identity, ok := UserFromContext(r.Context()); ok {
+			scope = "user:" + strconv.FormatInt(identity.UserID, 10)
+		}
+		storeKey := scope + ":" + key
+
+		// The outcome must be recorded even if the client goes away.
+		ctx := context.WithoutCancel(r.Context())
+
+		entry, err := h.idempotency.Begin(ctx, storeKey, fingerprint)
+		if err != nil {
+			h.log(r).Error("Idempotency store unavailable", slog.String("error", err.Error()))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if entry != nil {
+			h.respondToDuplicate(w, r, entry, fingerprint)
+			return
+		}
+
+		completed := false
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.idempotency.Release(ctx, storeKey); err != nil {
+				h.log(r).Error("Failed to release idempotency key", slog.String("error", err.Error()))
+			}
+		}()
+
+		var buf bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&buf)
+
+		keyCtx := idempotency.WithKey(r.Context(), storeKey)
+		next.ServeHTTP(ww, r.WithContext(keyCtx))
+
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+		if code >= http.StatusInternalServerError && !idempotency.Kept(keyCtx) {
+			return
+		}
+
+		header := make(http.Header)
+		for _, name := range replayedHeaders {
+			if value := ww.Header().Get(name); value != "" {
+				header.Set(name, value)
+			}
+		}
+
+		err = h.idempotency.Complete(ctx, storeKey, idempotency.Response{
+			StatusCode: code,
+			Header:     header,
+			Body:       buf.Bytes(),
+		})
+		if err != nil {
+			h.log(r).Error("Failed to store idempotent response", slog.String("error", err.Error()))
+			return
+		}
+		completed = true
+	})
+}
+
+func (h *HTTPHandler) respondToDuplicate(w http.ResponseWriter, r *http.Request, entry *idempotency.Entry, fingerprint string) {
+	switch {
+	case entry.Fingerprint != fingerprint:
+		h.log(r).Warn("Idempotency key reused for a different request", slog.String("path", r.URL.Path))
+		h.respondWithJSON(w, r, http.StatusUnprocessableEntity, errorResponse{
+			Error: "Idempotency-Key was already used for a different request",
+			Code:  errCodeIdempotencyReuse,
+		})
+	case entry.Response == nil:
+		h.respondWithError(w, r, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+	default:
+		for name, values := range entry.Response.Header {
+			w.Header()[name] = values
+		}
+		w.Header().Set(idempotentReplayHeader, "true")
+		w.WriteHeader(entry.Response.StatusCode)
+		w.Write(entry.Response.Body)
+	}
+}
+
+// requestFingerprint identifies a request by route and body, so that a key
+// cannot be replayed against a different endpoint or payload.
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
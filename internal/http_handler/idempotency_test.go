@@ -0,0 +1,217 @@
+package httphandler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/idempotency"
+	"ecomGateway/internal/processor"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const orderBody = `{"items":[{"product_id":7,"quantity":1}]}`
+
+// setupIdempotentOrders serves POST /orders behind the idempotency
+// middleware, with the user id taken from the X-Test-User header in place
+// of a token.
+func setupIdempotentOrders(proc processor.Processor) http.Handler {
+	h := NewHTTPHandler(proc, slog.Default(), Options{Idempotency: idempotency.NewMemoryStore(time.Hour)})
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := int64(1)
+			if r.Header.Get("X-Test-User") == "2" {
+				userID = 2
+			}
+			next.ServeHTTP(w, r.WithContext(withUserIdentity(r.Context(), UserIdentity{UserID: userID})))
+		})
+	})
+	router.With(h.idempotent).Post("/orders", h.createOrder)
+	return router
+}
+
+func postOrder(router http.Handler, key, body, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func countingOrders(calls *atomic.Int64) *stubProcessor {
+	return &stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			id := calls.Add(1)
+			return &processor.Order{ID: id, UserID: userID, TotalPrice: 100}, nil
+		},
+	}
+}
+
+func TestIdempotency_ReplaysFirstResponse(t *testing.T) {
+	var calls atomic.Int64
+	router := setupIdempotentOrders(countingOrders(&calls))
+
+	first := postOrder(router, "key-1", orderBody, "")
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	second := postOrder(router, "key-1", orderBody, "")
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "application/json", second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayHeader))
+	assert.EqualValues(t, 1, calls.Load(), "the order is created once")
+
+	postOrder(router, "", orderBody, "")
+	postOrder(router, "", orderBody, "")
+	assert.EqualValues(t, 3, calls.Load(), "requests without a key are not deduplicated")
+}
+
+func TestIdempotency_KeysAreScopedPerUser(t *testing.T) {
+	var calls atomic.Int64
+	router := setupIdempotentOrders(countingOrders(&calls))
+
+	require.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "1").Code)
+	require.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "2").Code)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestIdempotency_KeyReachesProcessor(t *testing.T) {
+	var keys []string
+	router := setupIdempotentOrders(&stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			keys = append(keys, idempotency.KeyFromContext(ctx))
+			return &processor.Order{ID: 1, UserID: userID}, nil
+		},
+	})
+
+	require.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "2").Code)
+	require.Equal(t, http.StatusCreated, postOrder(router, "", orderBody, "2").Code)
+	assert.Equal(t, []string{"user:2:key-1", ""}, keys, "the backend calls reuse the scoped key")
+}
+
+func TestIdempotency_KeyReusedWithDifferentBody(t *testing.T) {
+	var calls atomic.Int64
+	router := setupIdempotentOrders(countingOrders(&calls))
+
+	require.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "").Code)
+
+	rec := postOrder(router, "key-1", `{"items":[{"product_id":7,"quantity":5}]}`, "")
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errCodeIdempotencyReuse, resp.Code)
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestIdempotency_ConcurrentDuplicate(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	router := setupIdempotentOrders(&stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			close(started)
+			<-release
+			return &processor.Order{ID: 1, UserID: userID}, nil
+		},
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postOrder(router, "key-1", orderBody, "")
+	}()
+	<-started
+
+	rec := postOrder(router, "key-1", orderBody, "")
+	assert.Equal(t, http.StatusConflict, rec.Code, "the first request is still in flight")
+
+	close(release)
+	assert.Equal(t, http.StatusCreated, (<-done).Code)
+	assert.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "").Code, "replayed once complete")
+}
+
+func TestIdempotency_ServerErrorsAreNotStored(t *testing.T) {
+	var calls atomic.Int64
+	router := setupIdempotentOrders(&stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			if calls.Add(1) == 1 {
+				return nil, status.Error(codes.Unavailable, "order service down")
+			}
+			return &processor.Order{ID: 1, UserID: userID}, nil
+		},
+	})
+
+	require.Equal(t, http.StatusServiceUnavailable, postOrder(router, "key-1", orderBody, "").Code)
+	assert.Equal(t, http.StatusCreated, postOrder(router, "key-1", orderBody, "").Code, "the retry is executed")
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestIdempotency_KeptServerErrorIsReplayed(t *testing.T) {
+	var calls atomic.Int64
+	router := setupIdempotentOrders(&stubProcessor{
+		CreateOrderFunc: func(ctx context.Context, userID int64, items []processor.OrderItemInput) (*processor.Order, error) {
+			calls.Add(1)
+			idempotency.Keep(ctx)
+			return nil, &processor.OrderError{Err: processor.ErrOrderOutcomeUnknown}
+		},
+	})
+
+	first := postOrder(router, "key-1", orderBody, "")
+	require.Equal(t, http.StatusGatewayTimeout, first.Code)
+
+	second := postOrder(router, "key-1", orderBody, "")
+	assert.Equal(t, http.StatusGatewayTimeout, second.Code)
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayHeader))
+	assert.EqualValues(t, 1, calls.Load(), "a request that may have placed the order is not run again")
+}
+
+func TestIdempotency_Register(t *testing.T) {
+	var calls atomic.Int64
+	proc := &stubProcessor{
+		RegisterUserFunc: func(ctx context.Context, email, password, login string) (int64, error) {
+			return calls.Add(1), nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{Idempotency: idempotency.NewMemoryStore(time.Hour)})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	register := func(remoteAddr, login string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/register",
+			strings.NewReader(`{"email":"`+login+`@example.com","password":"secret","login":"`+login+`"}`))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(idempotencyKeyHeader, "signup-1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := register("192.0.2.1:1234", "alice")
+	require.Equal(t, http.StatusCreated, first.Code)
+	retried := register("198.51.100.7:5678", "alice")
+	assert.Equal(t, first.Body.String(), retried.Body.String())
+	assert.Equal(t, "true", retried.Header().Get(idempotentReplayHeader), "a retry from a new address is replayed")
+	assert.EqualValues(t, 1, calls.Load(), "the user is registered once")
+
+	other := register("192.0.2.1:1234", "bob")
+	require.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get(idempotentReplayHeader))
+	assert.EqualValues(t, 2, calls.Load(), "anonymous keys are scoped by the request body")
+}
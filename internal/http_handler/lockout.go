@@ -0,0 +1,91 @@
+package httphandler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ecomGateway/internal/lockout"
+)
+
+// LockoutOptions configures brute-force protection of /login. Failures are
+// counted per login name and per client IP independently.
+type LockoutOptions struct {
+	Login lockout.Policy
+	IP    lockout.Policy
+}
+
+// loginLockout tracks failed logins; nil trackers are disabled.
+type loginLockout struct {
+	logins *lockout.Tracker
+	ips    *lockout.Tracker
+}
+
+func newLoginLockout(opts LockoutOptions) loginLockout {
+	var l loginLockout
+	if opts.Login.Enabled() {
+		l.logins = lockout.NewTracker(opts.Login)
+	}
+	if opts.IP.Enabled() {
+		l.ips = lockout.NewTracker(opts.IP)
+	}
+	return l
+}
+
+// loginKey folds case so that variants of one login share a counter.
+func loginKey(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+func (l loginLockout) locked(login, ip string) bool {
+	return (l.logins != nil && l.logins.Locked(loginKey(login))) ||
+		(l.ips != nil && l.ips.Locked(ip))
+}
+
+// delay is what the next failure would wait, so that locked out attempts
+// take as long as failed ones.
+func (l loginLockout) delay(login, ip string) time.Duration {
+	var d time.Duration
+	if l.logins != nil {
+		d = max(d, l.logins.Delay(loginKey(login)))
+	}
+	if l.ips != nil {
+		d = max(d, l.ips.Delay(ip))
+	}
+	return d
+}
+
+func (l loginLockout) fail(login, ip string) time.Duration {
+	var d time.Duration
+	if l.logins != nil {
+		d = max(d, l.logins.Fail(loginKey(login)))
+	}
+	if l.ips != nil {
+		d = max(d, l.ips.Fail(ip))
+	}
+	return d
+}
+
+// reset forgets the failures of login after it succeeds. The client IP keeps
+// its count, so one valid account cannot clear the failures an address has
+// racked up guessing others; they expire with the IP policy's Duration.
+func (l loginLockout) reset(login string) {
+	if l.logins != nil {
+		l.logins.Reset(loginKey(login))
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
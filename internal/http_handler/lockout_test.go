@@ -0,0 +1,121 @@
+package httphandler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/lockout"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLogin_Lockout(t *testing.T) {
+	calls := 0
+	proc := &stubProcessor{
+		LoginUserFunc: func(ctx context.Context, login, password string) (string, error) {
+			calls++
+			if password == "secret" {
+				return "token", nil
+			}
+			return "", status.Error(codes.Unauthenticated, "invalid credentials")
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{
+		Lockout: LockoutOptions{
+			Login: lockout.Policy{Threshold: 2, Duration: time.Minute},
+			IP:    lockout.Policy{Threshold: 10, Duration: time.Minute},
+		},
+	})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	login := func(login, password string) *httptest.ResponseRecorder {
+		body := `{"login":"` + login + `","password":"` + password + `"}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return rec
+	}
+
+	failed := login("alice", "wrong")
+	require.Equal(t, http.StatusUnauthorized, failed.Code)
+	require.Equal(t, http.StatusUnauthorized, login("Alice", "wrong").Code)
+	require.Equal(t, 2, calls)
+
+	locked := login("alice", "secret")
+	assert.Equal(t, 2, calls, "locked out attempts do not reach the backend")
+	assert.Equal(t, failed.Code, locked.Code)
+	assert.Equal(t, failed.Body.String(), locked.Body.String(), "lockout must look like bad credentials")
+
+	assert.Equal(t, http.StatusOK, login("bob", "secret").Code, "other logins are not locked")
+}
+
+func TestLogin_SuccessResetsFailures(t *testing.T) {
+	proc := &stubProcessor{
+		LoginUserFunc: func(ctx context.Context, login, password string) (string, error) {
+			if password == "secret" {
+				return "token", nil
+			}
+			return "", status.Error(codes.Unauthenticated, "invalid credentials")
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{
+		Lockout: LockoutOptions{Login: lockout.Policy{Threshold: 2, Duration: time.Minute}},
+	})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	login := func(password string) int {
+		body := `{"login":"alice","password":"` + password + `"}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, login("wrong"))
+	require.Equal(t, http.StatusOK, login("secret"))
+	require.Equal(t, http.StatusUnauthorized, login("wrong"))
+	assert.Equal(t, http.StatusOK, login("secret"))
+}
+
+func TestLogin_SuccessKeepsIPFailures(t *testing.T) {
+	calls := 0
+	proc := &stubProcessor{
+		LoginUserFunc: func(ctx context.Context, login, password string) (string, error) {
+			calls++
+			if password == "secret" {
+				return "token", nil
+			}
+			return "", status.Error(codes.Unauthenticated, "invalid credentials")
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{
+		Lockout: LockoutOptions{IP: lockout.Policy{Threshold: 3, Duration: time.Minute}},
+	})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	login := func(login, password string) int {
+		body := `{"login":"` + login + `","password":"` + password + `"}`
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body)))
+		return rec.Code
+	}
+
+	require.Equal(t, http.StatusUnauthorized, login("bob", "wrong"))
+	require.Equal(t, http.StatusUnauthorized, login("carol", "wrong"))
+	require.Equal(t, http.StatusOK, login("alice", "secret"))
+	require.Equal(t, http.StatusUnauthorized, login("dave", "wrong"))
+	require.Equal(t, 4, calls)
+
+	assert.Equal(t, http.StatusUnauthorized, login("alice", "secret"), "a success must not clear the IP's failures")
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,88 @@
+package httphandler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ecomGateway/internal/processor"
+)
+
+// parseProductQuery reads the GET /products parameters: filter, sort, limit,
+// cursor, min_price, max_price and in_stock. Limits above the maximum are
+// capped rather than rejected.
+func parseProductQuery(values url.Values) (processor.ProductQuery, error) {
+	query := processor.ProductQuery{
+		Filter: values.Get("filter"),
+		Sort:   values.Get("sort"),
+		Cursor: values.Get("cursor"),
+	}
+
+	if raw := values.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return query, fmt.Errorf("limit must be a positive integer, got %q", raw)
+		}
+		query.Limit = min(limit, processor.MaxProductPageSize)
+	}
+
+	var err error
+	if query.MinPrice, err = parsePrice(values, "min_price"); err != nil {
+		return query, err
+	}
+	if query.MaxPrice, err = parsePrice(values, "max_price"); err != nil {
+		return query, err
+	}
+	if query.MinPrice != nil && query.MaxPrice != nil && *query.MinPrice > *query.MaxPrice {
+		return query, errors.New("min_price must not exceed max_price")
+	}
+
+	if raw := values.Get("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, fmt.Errorf("in_stock must be true or false, got %q", raw)
+		}
+		query.InStock = inStock
+	}
+
+	return query, nil
+}
+
+func parsePrice(values url.Values, name string) (*int64, error) {
+	raw := values.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || price < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative integer, got %q", name, raw)
+	}
+	return &price, nil
+}
+
+// setPageLinks advertises the neighbouring pages in a Link header (RFC 8288)
+// that repeats the request's other parameters.
+func setPageLinks(w http.ResponseWriter, r *http.Request, page *processor.ProductPage) {
+	var links []string
+	for _, link := range []struct{ rel, cursor string }{
+		{"next", page.NextCursor},
+		{"prev", page.PrevCursor},
+	} {
+		if link.cursor == "" {
+			continue
+		}
+
+		query := r.URL.Query()
+		query.Set("cursor", link.cursor)
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", target.String(), link.rel))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
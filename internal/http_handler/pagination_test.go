@@ -0,0 +1,82 @@
+package httphandler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"ecomGateway/internal/processor"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListProducts_Pagination(t *testing.T) {
+	var got processor.ProductQuery
+	proc := &stubProcessor{
+		ListProductsPageFunc: func(ctx context.Context, query processor.ProductQuery) (*processor.ProductPage, error) {
+			got = query
+			return &processor.ProductPage{
+				Products:   []processor.Product{{Id: 3}, {Id: 4}},
+				NextCursor: "next",
+				PrevCursor: "prev",
+			}, nil
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet,
+		"/products?sort=-price&limit=500&min_price=100&max_price=900&in_stock=true&cursor=old", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id":3,"name":"","description":"","price":0,"stock_count":0},{"id":4,"name":"","description":"","price":0,"stock_count":0}]`, rec.Body.String())
+
+	require.NotNil(t, got.MinPrice)
+	require.NotNil(t, got.MaxPrice)
+	assert.Equal(t, "-price", got.Sort)
+	assert.Equal(t, processor.MaxProductPageSize, got.Limit, "oversized limits are capped")
+	assert.EqualValues(t, 100, *got.MinPrice)
+	assert.EqualValues(t, 900, *got.MaxPrice)
+	assert.True(t, got.InStock)
+	assert.Equal(t, "old", got.Cursor)
+
+	assert.Equal(t,
+		`</products?cursor=next&in_stock=true&limit=500&max_price=900&min_price=100&sort=-price>; rel="next", `+
+			`</products?cursor=prev&in_stock=true&limit=500&max_price=900&min_price=100&sort=-price>; rel="prev"`,
+		rec.Header().Get("Link"))
+}
+
+func TestListProducts_InvalidQuery(t *testing.T) {
+	proc := &stubProcessor{
+		ListProductsPageFunc: func(ctx context.Context, query processor.ProductQuery) (*processor.ProductPage, error) {
+			return nil, fmt.Errorf("%w: unknown sort field", processor.ErrInvalidProductQuery)
+		},
+	}
+	h := NewHTTPHandler(proc, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	for _, query := range []string{
+		"limit=0",
+		"limit=ten",
+		"min_price=-1",
+		"max_price=1.5",
+		"min_price=10&max_price=5",
+		"in_stock=maybe",
+		"sort=rating",
+	} {
+		t.Run(query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products?"+query, nil))
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Empty(t, rec.Header().Get("Link"))
+		})
+	}
+}
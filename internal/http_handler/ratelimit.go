@@ -0,0 +1,96 @@
+package httphandler
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ecomGateway/internal/ratelimit"
+)
+
+// RateLimitOptions configures request throttling. A nil Store disables it
+// entirely; a Limit with zero Rate disables that limit only.
+type RateLimitOptions struct {
+	Store ratelimit.Store
+	// IP applies per client address to every route, Auth additionally to
+	// /login and /register, and User per user_id to authenticated routes.
+	IP   ratelimit.Limit
+	Auth ratelimit.Limit
+	User ratelimit.Limit
+}
+
+type rateLimiters struct {
+	ip   *ratelimit.Limiter
+	auth *ratelimit.Limiter
+	user *ratelimit.Limiter
+}
+
+func newRateLimiters(opts RateLimitOptions) rateLimiters {
+	if opts.Store == nil {
+		return rateLimiters{}
+	}
+
+	var limiters rateLimiters
+	if opts.IP.Enabled() {
+		limiters.ip = ratelimit.NewLimiter(opts.Store, "ip", opts.IP)
+	}
+	if opts.Auth.Enabled() {
+		limiters.auth = ratelimit.NewLimiter(opts.Store, "auth", opts.Auth)
+	}
+	if opts.User.Enabled() {
+		limiters.user = ratelimit.NewLimiter(opts.Store, "user", opts.User)
+	}
+	return limiters
+}
+
+func (h *HTTPHandler) limitByIP(next http.Handler) http.Handler {
+	return h.rateLimit(h.limiters.ip, next, func(r *http.Request) string {
+		return h.clientIP.ClientIP(r)
+	})
+}
+
+func (h *HTTPHandler) limitAuth(next http.Handler) http.Handler {
+	return h.rateLimit(h.limiters.auth, next, func(r *http.Request) string {
+		return h.clientIP.ClientIP(r)
+	})
+}
+
+// limitByUser must run after authenticate.
+func (h *HTTPHandler) limitByUser(next http.Handler) http.Handler {
+	return h.rateLimit(h.limiters.user, next, func(r *http.Request) string {
+		identity, _ := UserFromContext(r.Context())
+		return strconv.FormatInt(identity.UserID, 10)
+	})
+}
+
+// rateLimit rejects requests over limiter's budget with 429 and Retry-After.
+// Store failures let the request through rather than take the gateway down.
+func (h *HTTPHandler) rateLimit(limiter *ratelimit.Limiter, next http.Handler, key func(*http.Request) string) http.Handler {
+	if limiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		k := key(r)
+		allowed, retryAfter, err := limiter.Allow(r.Context(), k)
+		if err != nil {
+			h.log(r).Error("Rate limiter unavailable", slog.String("error", err.Error()))
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !allowed {
+			h.log(r).Warn("Rate limit exceeded", slog.String("key", k), slog.String("path", r.URL.Path))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAfter)))
+			h.respondWithError(w, r, http.StatusTooManyRequests, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// retryAfterSeconds rounds up so that clients never retry too early.
+func retryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,62 @@
+package httphandler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ecomGateway/internal/ratelimit"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit_AuthRoutes(t *testing.T) {
+	h := NewHTTPHandler(nil, slog.Default(), Options{
+		RateLimit: RateLimitOptions{
+			Store: ratelimit.NewMemoryStore(),
+			IP:    ratelimit.Limit{Rate: 100, Burst: 100},
+			Auth:  ratelimit.Limit{Rate: 0.5, Burst: 2},
+		},
+	})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	login := func(remoteAddr string) *httptest.ResponseRecorder {
+		// Malformed JSON is rejected before the processor is called.
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("{"))
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusBadRequest, login("203.0.113.7:1000").Code)
+	assert.Equal(t, http.StatusBadRequest, login("203.0.113.7:1001").Code)
+
+	rec := login("203.0.113.7:1002")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, errCodeRateLimited, resp.Code)
+
+	assert.Equal(t, http.StatusBadRequest, login("198.51.100.2:1000").Code, "other clients are not affected")
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	h := NewHTTPHandler(nil, slog.Default(), Options{})
+	router := chi.NewRouter()
+	h.RegisterRoutes(router)
+
+	for i := 0; i < 20; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("{")))
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package httphandler
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RedirectToHTTPS answers every request with a permanent redirect to the same
+// URL over HTTPS, on the port of httpsAddress. Methods other than GET and
+// HEAD get 308 so that clients repeat them with the body.
+func RedirectToHTTPS(httpsAddress string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddress)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		host = strings.Trim(host, "[]")
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		target := url.URL{
+			Scheme:   "https",
+			Host:     host,
+			Path:     r.URL.Path,
+			RawPath:  r.URL.RawPath,
+			RawQuery: r.URL.RawQuery,
+		}
+
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, target.String(), code)
+	})
+}
@@ -0,0 +1,71 @@
+package httphandler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpsAddress string
+		method       string
+		target       string
+		host         string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "default port",
+			httpsAddress: ":443",
+			method:       http.MethodGet,
+			target:       "/products/7?page=2",
+			host:         "shop.example.com:80",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "https://shop.example.com/products/7?page=2",
+		},
+		{
+			name:         "custom port",
+			httpsAddress: ":8443",
+			method:       http.MethodGet,
+			target:       "/me",
+			host:         "localhost:8080",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "https://localhost:8443/me",
+		},
+		{
+			name:         "ipv6 host",
+			httpsAddress: ":8443",
+			method:       http.MethodGet,
+			target:       "/",
+			host:         "[::1]:8080",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "https://[::1]:8443/",
+		},
+		{
+			name:         "post keeps the method",
+			httpsAddress: ":443",
+			method:       http.MethodPost,
+			target:       "/orders",
+			host:         "shop.example.com",
+			wantCode:     http.StatusPermanentRedirect,
+			wantLocation: "https://shop.example.com/orders",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			RedirectToHTTPS(tt.httpsAddress).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantLocation, rec.Header().Get("Location"))
+		})
+	}
+}
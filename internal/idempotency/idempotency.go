@@ -0,0 +1,139 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	ttlmap "ecomGateway/internal/lib/ttl_map"
+)
+
+// Response is what the first request with a key produced; it is replayed for
+// its duplicates.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Entry is the state of a key. Response is nil while the first request is
+// still in flight.
+type Entry struct {
+	Fingerprint string
+	Response    *Response
+}
+
+// Store keeps idempotency keys. A retry can reach another gateway instance
+// than the request it repeats, and MemoryStore would not recognise it there,
+// so replicated gateways need a store they all share.
+type Store interface {
+	// Begin claims key for a request with fingerprint. If the key is new it
+	// returns nil and the caller must Complete or Release it; otherwise it
+	// returns the existing entry and claims nothing.
+	Begin(ctx context.Context, key, fingerprint string) (*Entry, error)
+	// Complete stores the response for a claimed key.
+	Complete(ctx context.Context, key string, resp Response) error
+	// Release forgets a claimed key so that the request may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+type keyContextKey struct{}
+
+type requestKey struct {
+	key  string
+	keep atomic.Bool
+}
+
+// WithKey records the client-scoped Idempotency-Key of the request ctx
+// belongs to, so that the backend calls made for it can reuse the key.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyContextKey{}, &requestKey{key: key})
+}
+
+// KeyFromContext returns the key recorded by WithKey, if any.
+func KeyFromContext(ctx context.Context) string {
+	if k, ok := ctx.Value(keyContextKey{}).(*requestKey); ok {
+		return k.key
+	}
+	return ""
+}
+
+// Keep marks the request's key to be kept with its response even if that is
+// a server error: the request may have taken effect, so a retry has to be
+// answered from the stored response instead of being run again.
+func Keep(ctx context.Context) {
+	if k, ok := ctx.Value(keyContextKey{}).(*requestKey); ok {
+		k.keep.Store(true)
+	}
+}
+
+// Kept reports whether Keep was called for the key recorded in ctx.
+func Kept(ctx context.Context) bool {
+	k, ok := ctx.Value(keyContextKey{}).(*requestKey)
+	return ok && k.keep.Load()
+}
+
+// sweepInterval is how often MemoryStore drops expired keys.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	Entry
+	expires time.Time
+}
+
+// MemoryStore keeps keys, in flight or completed, for ttl after they were
+// claimed.
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries *ttlmap.Map[string, *memoryEntry]
+	now     func() time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl: ttl,
+		entries: ttlmap.New[string](sweepInterval, func(e *memoryEntry, now time.Time) bool {
+			return !now.Before(e.expires)
+		}),
+		now: time.Now,
+	}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if e, ok := s.entries.Get(key, now); ok {
+		entry := e.Entry
+		return &entry, nil
+	}
+
+	s.entries.Set(key, &memoryEntry{
+		Entry:   Entry{Fingerprint: fingerprint},
+		expires: now.Add(s.ttl),
+	})
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, resp Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries.Get(key, s.now()); ok {
+		e.Response = &resp
+	}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries.Delete(key)
+	return nil
+}
@@ -0,0 +1,74 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(ttl time.Duration) (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(ttl)
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour)
+
+	entry, err := store.Begin(ctx, "k", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, entry, "a new key is claimed")
+
+	entry, err = store.Begin(ctx, "k", "fp")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, "fp", entry.Fingerprint)
+	assert.Nil(t, entry.Response, "the first request is still in flight")
+
+	resp := Response{StatusCode: http.StatusCreated, Body: []byte(`{"order_id":1}`)}
+	require.NoError(t, store.Complete(ctx, "k", resp))
+
+	entry, err = store.Begin(ctx, "k", "other")
+	require.NoError(t, err)
+	require.NotNil(t, entry)
+	assert.Equal(t, "fp", entry.Fingerprint)
+	assert.Equal(t, &resp, entry.Response)
+}
+
+func TestMemoryStore_Release(t *testing.T) {
+	ctx := context.Background()
+	store, _ := newTestStore(time.Hour)
+
+	_, err := store.Begin(ctx, "k", "fp")
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, "k"))
+
+	entry, err := store.Begin(ctx, "k", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, entry, "a released key can be claimed again")
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store, now := newTestStore(time.Hour)
+
+	_, err := store.Begin(ctx, "old", "fp")
+	require.NoError(t, err)
+	require.NoError(t, store.Complete(ctx, "old", Response{StatusCode: http.StatusOK}))
+
+	*now = now.Add(time.Hour)
+	entry, err := store.Begin(ctx, "new", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+	assert.Equal(t, 1, store.entries.Len(), "expired keys are swept")
+
+	entry, err = store.Begin(ctx, "old", "fp")
+	require.NoError(t, err)
+	assert.Nil(t, entry, "an expired key is claimed anew")
+}
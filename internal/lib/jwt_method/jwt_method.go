@@ -4,6 +4,8 @@ import (
 	"crypto/rsa"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -34,15 +36,51 @@ func ParseJWT(tokenString string, publicKey *rsa.PublicKey) (map[string]interfac
 		return nil, errors.New("error parse claims")
 	}
 
-	if claims.ExpiresAt != nil && claims.ExpiresAt.Time.Before(time.Now()) {
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("token has no expiration")
+	}
+
+	if claims.ExpiresAt.Time.Before(time.Now()) {
 		return nil, errors.New("expired token")
 	}
 
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+
 	payload := map[string]interface{}{
 		"user_id": claims.UserID,
 		"exp":     claims.ExpiresAt.Time,
-		"iat":     claims.IssuedAt.Time,
+		"iat":     issuedAt,
 	}
 
 	return payload, nil
 }
+
+// LoadPublicKey reads the RSA public key used to verify tokens. The key is
+// taken from the PEM file at path or, if path is empty, from pemData itself.
+// Escaped "\n" sequences in pemData are accepted so the key can be passed
+// through a single-line environment variable.
+func LoadPublicKey(path, pemData string) (*rsa.PublicKey, error) {
+	var raw []byte
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read public key file: %w", err)
+		}
+		raw = data
+	case pemData != "":
+		raw = []byte(strings.ReplaceAll(pemData, `\n`, "\n"))
+	default:
+		return nil, errors.New("public key is not configured")
+	}
+
+	key, err := jwt.ParseRSAPublicKeyFromPEM(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,78 @@
+package requestid
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func serve(t *testing.T, header string) (string, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(Header, header)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return seen, rec
+}
+
+func TestMiddleware_KeepsIncomingID(t *testing.T) {
+	seen, rec := serve(t, "req-123")
+
+	assert.Equal(t, "req-123", seen)
+	assert.Equal(t, "req-123", rec.Header().Get(Header))
+}
+
+func TestMiddleware_GeneratesID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"contains spaces", "abc def"},
+		{"contains newline", "abc\ninjected: 1"},
+		{"too long", strings.Repeat("a", maxLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen, rec := serve(t, tt.header)
+
+			assert.Len(t, seen, 32)
+			assert.NotEqual(t, tt.header, seen)
+			assert.Equal(t, seen, rec.Header().Get(Header))
+		})
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	interceptor := UnaryClientInterceptor()
+
+	ctx := NewContext(context.Background(), "req-123")
+	require.NoError(t, interceptor(ctx, "/svc/Method", nil, nil, nil, invoker))
+	assert.Equal(t, []string{"req-123"}, outgoing.Get(MetadataKey))
+
+	outgoing = nil
+	require.NoError(t, interceptor(context.Background(), "/svc/Method", nil, nil, nil, invoker))
+	assert.Empty(t, outgoing.Get(MetadataKey))
+}
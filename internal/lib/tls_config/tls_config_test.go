@@ -0,0 +1,369 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testServerName = "user.internal"
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a PEM certificate and key for name, valid for both server and
+// client authentication.
+func (ca *testCA) issue(t *testing.T, name string) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) keyPair(t *testing.T, name string) tls.Certificate {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, name)
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	return pair
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// setupTLSServer starts a health server behind TLS on bufconn. When clientCAs
+// is set, the server requires a client certificate signed by it.
+func setupTLSServer(t *testing.T, serverCert tls.Certificate, clientCAs *x509.CertPool) func(context.Context, string) (net.Conn, error) {
+	t.Helper()
+
+	cfg := &tls.Config{Certificates: []tls.Certificate{serverCert}}
+	if clientCAs != nil {
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	lis := bufconn.Listen(1024 * 1024)
+	srv := grpc.NewServer(grpc.Creds(credentials.NewTLS(cfg)))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+}
+
+func check(t *testing.T, dialer func(context.Context, string) (net.Conn, error), creds credentials.TransportCredentials) error {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(dialer),
+		grpc.WithTransportCredentials(creds),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(false))
+	return err
+}
+
+func TestClientCredentials_CustomCA(t *testing.T) {
+	ca := newTestCA(t, "backend CA")
+	dialer := setupTLSServer(t, ca.keyPair(t, testServerName), nil)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	writeFile(t, caFile, ca.pem)
+
+	creds, err := NewClientCredentials(discardLogger, ClientOptions{CAFile: caFile, ServerName: testServerName})
+	require.NoError(t, err)
+	assert.NoError(t, check(t, dialer, creds))
+
+	wrongName, err := NewClientCredentials(discardLogger, ClientOptions{CAFile: caFile, ServerName: "order.internal"})
+	require.NoError(t, err)
+	assert.Error(t, check(t, dialer, wrongName), "the server name must match the certificate")
+
+	systemRoots, err := NewClientCredentials(discardLogger, ClientOptions{ServerName: testServerName})
+	require.NoError(t, err)
+	assert.Error(t, check(t, dialer, systemRoots), "a private CA is not in the system roots")
+}
+
+func TestClientCredentials_MutualTLS(t *testing.T) {
+	ca := newTestCA(t, "backend CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	dialer := setupTLSServer(t, ca.keyPair(t, testServerName), pool)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writeFile(t, caFile, ca.pem)
+	certPEM, keyPEM := ca.issue(t, "gateway")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	withoutCert, err := NewClientCredentials(discardLogger, ClientOptions{CAFile: caFile, ServerName: testServerName})
+	require.NoError(t, err)
+	assert.Error(t, check(t, dialer, withoutCert), "the server requires a client certificate")
+
+	creds, err := NewClientCredentials(discardLogger, ClientOptions{
+		CAFile:     caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		ServerName: testServerName,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, check(t, dialer, creds))
+}
+
+func TestClientCredentials_ReloadsRotatedFiles(t *testing.T) {
+	oldCA := newTestCA(t, "old CA")
+	newCA := newTestCA(t, "new CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(newCA.cert)
+	dialer := setupTLSServer(t, newCA.keyPair(t, testServerName), pool)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client-key.pem")
+	writeFile(t, caFile, oldCA.pem)
+	certPEM, keyPEM := oldCA.issue(t, "gateway")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	creds, err := NewClientCredentials(discardLogger, ClientOptions{
+		CAFile:         caFile,
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ServerName:     testServerName,
+		ReloadInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.Error(t, check(t, dialer, creds), "the server was already rotated to the new CA")
+
+	writeFile(t, caFile, newCA.pem)
+	certPEM, keyPEM = newCA.issue(t, "gateway")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	time.Sleep(5 * time.Millisecond)
+
+	assert.NoError(t, check(t, dialer, creds), "rotated files are used without a restart")
+}
+
+func TestClientCredentials_KeepsPreviousOnBadReload(t *testing.T) {
+	ca := newTestCA(t, "backend CA")
+	dialer := setupTLSServer(t, ca.keyPair(t, testServerName), nil)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeFile(t, caFile, ca.pem)
+
+	creds, err := NewClientCredentials(discardLogger, ClientOptions{
+		CAFile:         caFile,
+		ServerName:     testServerName,
+		ReloadInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	writeFile(t, caFile, []byte("half-written"))
+	time.Sleep(5 * time.Millisecond)
+
+	assert.NoError(t, check(t, dialer, creds))
+}
+
+func TestNewClientCredentials_InvalidOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := NewClientCredentials(discardLogger, ClientOptions{CertFile: filepath.Join(dir, "client.pem")})
+	assert.Error(t, err, "a certificate without a key")
+
+	_, err = NewClientCredentials(discardLogger, ClientOptions{CAFile: filepath.Join(dir, "missing.pem")})
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.pem")
+	writeFile(t, empty, nil)
+	_, err = NewClientCredentials(discardLogger, ClientOptions{CAFile: empty})
+	assert.Error(t, err, "a CA bundle without certificates")
+}
+
+// serveTLS accepts connections on a local listener and completes their
+// handshakes.
+func serveTLS(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.(*tls.Conn).Handshake()
+			_ = conn.Close()
+		}
+	}()
+
+	return lis.Addr().String()
+}
+
+func dialTLS(addr string, cfg *tls.Config) (*x509.Certificate, error) {
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestServerConfig_ReloadsRotatedCertificate(t *testing.T) {
+	ca := newTestCA(t, "gateway CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "gateway.local")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	cfg, err := NewServerConfig(discardLogger, ServerOptions{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: time.Millisecond,
+	})
+	require.NoError(t, err)
+	addr := serveTLS(t, cfg)
+
+	client := &tls.Config{RootCAs: pool, ServerName: "gateway.local"}
+	first, err := dialTLS(addr, client)
+	require.NoError(t, err)
+
+	certPEM, keyPEM = ca.issue(t, "gateway.local")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	time.Sleep(5 * time.Millisecond)
+
+	second, err := dialTLS(addr, client)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.SerialNumber, second.SerialNumber, "the rotated certificate is served without a restart")
+}
+
+func TestServerConfig_MinVersion(t *testing.T) {
+	ca := newTestCA(t, "gateway CA")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	certPEM, keyPEM := ca.issue(t, "gateway.local")
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	cfg, err := NewServerConfig(discardLogger, ServerOptions{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+	addr := serveTLS(t, cfg)
+
+	_, err = dialTLS(addr, &tls.Config{RootCAs: pool, ServerName: "gateway.local", MaxVersion: tls.VersionTLS12})
+	assert.Error(t, err, "TLS 1.2 clients are refused")
+
+	_, err = dialTLS(addr, &tls.Config{RootCAs: pool, ServerName: "gateway.local"})
+	assert.NoError(t, err)
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, ids)
+
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	assert.Error(t, err, "insecure suites are rejected")
+
+	ids, err = ParseCipherSuites(nil)
+	require.NoError(t, err)
+	assert.Nil(t, ids, "no list keeps the Go defaults")
+}
@@ -0,0 +1,64 @@
+package ttlmap
+
+import "time"
+
+// Map holds entries until they expire. An expired entry is dropped when it is
+// looked up, and all expired entries are swept at most once per interval so
+// that keys which are never looked up again do not pile up. A Map is not safe
+// for concurrent use; its owner guards it with its own lock.
+type Map[K comparable, V any] struct {
+	entries   map[K]V
+	expired   func(v V, now time.Time) bool
+	interval  time.Duration
+	lastSweep time.Time
+}
+
+// New returns a Map that sweeps every interval and treats an entry as gone
+// once expired reports true for it.
+func New[K comparable, V any](interval time.Duration, expired func(v V, now time.Time) bool) *Map[K, V] {
+	return &Map[K, V]{
+		entries:  make(map[K]V),
+		expired:  expired,
+		interval: interval,
+	}
+}
+
+// Get returns the live entry for key, sweeping the map first if it is due.
+func (m *Map[K, V]) Get(key K, now time.Time) (V, bool) {
+	if now.Sub(m.lastSweep) >= m.interval {
+		m.sweep(now)
+	}
+
+	v, ok := m.entries[key]
+	if ok && m.expired(v, now) {
+		delete(m.entries, key)
+		ok = false
+	}
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return v, true
+}
+
+func (m *Map[K, V]) Set(key K, v V) {
+	m.entries[key] = v
+}
+
+func (m *Map[K, V]) Delete(key K) {
+	delete(m.entries, key)
+}
+
+// Len counts the entries held, including expired ones not swept yet.
+func (m *Map[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *Map[K, V]) sweep(now time.Time) {
+	for key, v := range m.entries {
+		if m.expired(v, now) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
@@ -0,0 +1,49 @@
+package ttlmap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap_Expiry(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New[string, time.Time](time.Minute, func(expires, now time.Time) bool {
+		return !now.Before(expires)
+	})
+
+	m.Set("short", start.Add(10*time.Second))
+	m.Set("long", start.Add(time.Hour))
+
+	v, ok := m.Get("short", start)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Second), v)
+
+	_, ok = m.Get("short", start.Add(10*time.Second))
+	assert.False(t, ok, "an expired entry is gone")
+	assert.Equal(t, 1, m.Len(), "and dropped on lookup")
+
+	m.Delete("long")
+	_, ok = m.Get("long", start)
+	assert.False(t, ok)
+}
+
+func TestMap_Sweep(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := New[string, time.Time](time.Minute, func(expires, now time.Time) bool {
+		return !now.Before(expires)
+	})
+
+	m.Get("", start)
+	m.Set("old", start.Add(time.Second))
+	m.Set("new", start.Add(time.Hour))
+
+	m.Get("other", start.Add(30*time.Second))
+	assert.Equal(t, 2, m.Len(), "no sweep before the interval")
+
+	m.Get("other", start.Add(time.Minute))
+	assert.Equal(t, 1, m.Len(), "expired entries are swept")
+	_, ok := m.Get("new", start.Add(time.Minute))
+	assert.True(t, ok)
+}
@@ -0,0 +1,117 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+
+	ttlmap "ecomGateway/internal/lib/ttl_map"
+)
+
+// Policy configures how a Tracker reacts to consecutive failures. After each
+// failure the caller should wait BaseDelay doubled per previous failure, up
+// to MaxDelay; at Threshold failures the key is locked for Duration. Failures
+// older than Duration are forgotten. A zero Threshold disables the tracker.
+type Policy struct {
+	Threshold int
+	Duration  time.Duration
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func (p Policy) Enabled() bool {
+	return p.Threshold > 0
+}
+
+type entry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// Tracker counts consecutive failures per key in memory.
+type Tracker struct {
+	mu      sync.Mutex
+	policy  Policy
+	entries *ttlmap.Map[string, *entry]
+	now     func() time.Time
+}
+
+func NewTracker(policy Policy) *Tracker {
+	return &Tracker{
+		policy: policy,
+		// Entries live for Duration, so sweeping more often finds nothing.
+		entries: ttlmap.New[string](policy.Duration, func(e *entry, now time.Time) bool {
+			return !now.Before(e.lockedUntil) && now.Sub(e.lastFailure) >= policy.Duration
+		}),
+		now: time.Now,
+	}
+}
+
+// Locked reports whether key is locked out.
+func (t *Tracker) Locked(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e, ok := t.entries.Get(key, now)
+	return ok && now.Before(e.lockedUntil)
+}
+
+// Delay returns the delay the next failure of key would get.
+func (t *Tracker) Delay(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	failures := 1
+	if e, ok := t.entries.Get(key, t.now()); ok {
+		failures = e.failures + 1
+	}
+	return t.delay(failures)
+}
+
+// Fail records a failure of key, locking it once the threshold is reached,
+// and returns the delay to apply before responding.
+func (t *Tracker) Fail(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	e, ok := t.entries.Get(key, now)
+	if !ok {
+		e = &entry{}
+		t.entries.Set(key, e)
+	}
+	e.failures++
+	e.lastFailure = now
+	if e.failures >= t.policy.Threshold {
+		e.lockedUntil = now.Add(t.policy.Duration)
+	}
+
+	return t.delay(e.failures)
+}
+
+// Reset forgets the failures of key.
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.entries.Delete(key)
+}
+
+func (t *Tracker) delay(failures int) time.Duration {
+	if t.policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := t.policy.BaseDelay
+	for i := 1; i < failures; i++ {
+		delay *= 2
+		if t.policy.MaxDelay > 0 && delay >= t.policy.MaxDelay {
+			return t.policy.MaxDelay
+		}
+	}
+	if t.policy.MaxDelay > 0 && delay > t.policy.MaxDelay {
+		return t.policy.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTracker(policy Policy) (*Tracker, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := NewTracker(policy)
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestTracker_ProgressiveDelayAndLockout(t *testing.T) {
+	tracker, now := newTestTracker(Policy{
+		Threshold: 3,
+		Duration:  time.Minute,
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  300 * time.Millisecond,
+	})
+
+	assert.Equal(t, 100*time.Millisecond, tracker.Delay("alice"))
+	assert.Equal(t, 100*time.Millisecond, tracker.Fail("alice"))
+	assert.False(t, tracker.Locked("alice"))
+
+	assert.Equal(t, 200*time.Millisecond, tracker.Fail("alice"))
+	assert.False(t, tracker.Locked("alice"))
+
+	assert.Equal(t, 300*time.Millisecond, tracker.Fail("alice"), "delay is capped")
+	assert.True(t, tracker.Locked("alice"))
+	assert.False(t, tracker.Locked("bob"))
+
+	*now = now.Add(time.Minute)
+	assert.False(t, tracker.Locked("alice"), "lockout expires")
+	assert.Equal(t, 100*time.Millisecond, tracker.Fail("alice"), "expired failures are forgotten")
+}
+
+func TestTracker_Reset(t *testing.T) {
+	tracker, _ := newTestTracker(Policy{Threshold: 2, Duration: time.Minute})
+
+	tracker.Fail("alice")
+	tracker.Reset("alice")
+	tracker.Fail("alice")
+
+	assert.False(t, tracker.Locked("alice"), "failures must be consecutive")
+
+	tracker.Fail("alice")
+	assert.True(t, tracker.Locked("alice"))
+}
+
+func TestTracker_Sweep(t *testing.T) {
+	tracker, now := newTestTracker(Policy{Threshold: 5, Duration: time.Minute})
+
+	tracker.Fail("old")
+	*now = now.Add(2 * time.Minute)
+	tracker.Fail("new")
+
+	assert.Equal(t, 1, tracker.entries.Len(), "expired failures are swept")
+}
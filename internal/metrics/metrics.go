@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"ecomGateway/internal/cache"
+	"ecomGateway/internal/grpc/interceptors"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "gateway"
+
+// unmatchedRoute labels requests that did not match any route so arbitrary
+// paths cannot blow up the label cardinality.
+const unmatchedRoute = "unmatched"
+
+var registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by route pattern and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by route pattern and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "code"})
+
+	httpInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "http_requests_in_flight",
+		Help:      "HTTP requests currently being served, by route pattern.",
+	}, []string{"method", "route"})
+
+	grpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_requests_total",
+		Help:      "Outbound gRPC attempts, by backend, method and status code.",
+	}, []string{"backend", "method", "code"})
+
+	grpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_request_duration_seconds",
+		Help:      "Outbound gRPC attempt latency, by backend, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"backend", "method", "code"})
+
+	grpcRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_retries_total",
+		Help:      "Outbound gRPC attempts that were retries of a failed call.",
+	}, []string{"backend", "method"})
+
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_client_breaker_transitions_total",
+		Help:      "Circuit breaker state transitions, by backend.",
+	}, []string{"backend", "from", "to"})
+
+	breakers = &breakerCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "grpc_client_breaker_state"),
+			"Circuit breaker state by backend: 0 closed, 1 half-open, 2 open.",
+			[]string{"backend"}, nil,
+		),
+		breakers: make(map[string]*interceptors.Breaker),
+	}
+
+	caches = &cacheCollector{
+		hits: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cache_hits_total"),
+			"Cache lookups served from the cache, by cache.",
+			[]string{"cache"}, nil,
+		),
+		misses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cache_misses_total"),
+			"Cache lookups that had to load the value, by cache.",
+			[]string{"cache"}, nil,
+		),
+		evictions: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cache_evictions_total"),
+			"Entries evicted to stay within the cache size, by cache.",
+			[]string{"cache"}, nil,
+		),
+		entries: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "cache_entries"),
+			"Entries currently held, by cache.",
+			[]string{"cache"}, nil,
+		),
+		caches: make(map[string]statsSource),
+	}
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		httpInFlight,
+		grpcRequests,
+		grpcDuration,
+		grpcRetries,
+		breakerTransitions,
+		breakers,
+		caches,
+	)
+}
+
+// Handler serves the gateway metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Middleware records HTTP metrics labelled with the chi route pattern. The
+// pattern is resolved against routes up front so in-flight requests can be
+// attributed to their route too.
+func Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := unmatchedRoute
+			rctx := chi.NewRouteContext()
+			if routes.Match(rctx, r.Method, r.URL.Path) {
+				route = rctx.RoutePattern()
+			}
+
+			inFlight := httpInFlight.WithLabelValues(r.Method, route)
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			start := time.Now()
+
+			next.ServeHTTP(ww, r)
+
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			labels := []string{r.Method, route, strconv.Itoa(code)}
+			httpRequests.WithLabelValues(labels...).Inc()
+			httpDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// UnaryClientInterceptor records metrics for every attempt made to backend.
+// It must sit after the retry interceptor in the chain so that retries are
+// seen as separate attempts.
+func UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if isRetryAttempt(ctx) {
+			grpcRetries.WithLabelValues(backend, method).Inc()
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		code := status.Code(err).String()
+		grpcRequests.WithLabelValues(backend, method, code).Inc()
+		grpcDuration.WithLabelValues(backend, method, code).Observe(time.Since(start).Seconds())
+
+		return err
+	}
+}
+
+func isRetryAttempt(ctx context.Context) bool {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	return ok && len(md.Get(grpcretry.AttemptMetadataKey)) > 0
+}
+
+// ObserveBreaker exports the state of b, replacing any breaker previously
+// registered under the same name.
+func ObserveBreaker(b *interceptors.Breaker) {
+	breakers.mu.Lock()
+	defer breakers.mu.Unlock()
+
+	breakers.breakers[b.Name()] = b
+}
+
+// BreakerStateChanged counts a transition; it is meant to be passed to
+// interceptors.NewBreaker.
+func BreakerStateChanged(backend string, from, to interceptors.BreakerState) {
+	breakerTransitions.WithLabelValues(backend, from.String(), to.String()).Inc()
+}
+
+// breakerCollector reads breaker states at scrape time so that the cooldown
+// expiring is reported without waiting for the next call.
+type breakerCollector struct {
+	desc *prometheus.Desc
+
+	mu       sync.Mutex
+	breakers map[string]*interceptors.Breaker
+}
+
+func (c *breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, b := range c.breakers {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(b.State()), name)
+	}
+}
+
+type statsSource interface {
+	Name() string
+	Stats() cache.Stats
+}
+
+// ObserveCache exports the hit, miss and eviction counts of c, replacing any
+// cache previously registered under the same name.
+func ObserveCache(c statsSource) {
+	caches.mu.Lock()
+	defer caches.mu.Unlock()
+
+	caches.caches[c.Name()] = c
+}
+
+// cacheCollector reads the counters kept by the caches at scrape time.
+type cacheCollector struct {
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+	entries   *prometheus.Desc
+
+	mu     sync.Mutex
+	caches map[string]statsSource
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+	ch <- c.entries
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, source := range c.caches {
+		stats := source.Stats()
+		ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits), name)
+		ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses), name)
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions), name)
+		ch <- prometheus.MustNewConstMetric(c.entries, prometheus.GaugeValue, float64(stats.Entries), name)
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/cache"
+
+	"github.com/go-chi/chi"
+	grpcretry "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/retry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMiddleware_RoutePattern(t *testing.T) {
+	router := chi.NewRouter()
+	router.Use(Middleware(router))
+	router.Get("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 1.0, testutil.ToFloat64(httpInFlight.WithLabelValues(http.MethodGet, "/items/{id}")))
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/items/{id}", "418")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(httpInFlight.WithLabelValues(http.MethodGet, "/items/{id}")))
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	const method = "/test.Service/Get"
+	interceptor := UnaryClientInterceptor("test")
+
+	failing := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return status.Error(codes.Unavailable, "down")
+	}
+	succeeding := func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+		return nil
+	}
+
+	err := interceptor(context.Background(), method, nil, nil, nil, failing)
+	require.Error(t, err)
+
+	retryCtx := metadata.AppendToOutgoingContext(context.Background(), grpcretry.AttemptMetadataKey, "1")
+	err = interceptor(retryCtx, method, nil, nil, nil, succeeding)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(grpcRequests.WithLabelValues("test", method, codes.Unavailable.String())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(grpcRequests.WithLabelValues("test", method, codes.OK.String())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(grpcRetries.WithLabelValues("test", method)))
+}
+
+func TestHandler(t *testing.T) {
+	httpRequests.WithLabelValues(http.MethodGet, "/handler", "200").Inc()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `gateway_http_requests_total{code="200",method="GET",route="/handler"} 1`)
+}
+
+func TestObserveCache(t *testing.T) {
+	c := cache.New[int, string]("metrics_test", 10, time.Minute)
+	ObserveCache(c)
+
+	load := func(context.Context) (string, error) { return "v", nil }
+	c.Get(context.Background(), 1, load)
+	c.Get(context.Background(), 1, load)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Contains(t, body, `gateway_cache_hits_total{cache="metrics_test"} 1`)
+	assert.Contains(t, body, `gateway_cache_misses_total{cache="metrics_test"} 1`)
+	assert.Contains(t, body, `gateway_cache_entries{cache="metrics_test"} 1`)
+}
@@ -0,0 +1,251 @@
+package processor
+
+import (
+	"context"
+	"ecomGateway/internal/cart"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+var (
+	ErrCartItemNotFound   = errors.New("product is not in the cart")
+	ErrCartFull           = errors.New("cart is full")
+	ErrEmptyCart          = errors.New("cart is empty")
+	ErrCheckoutInProgress = errors.New("cart checkout is already in progress")
+)
+
+// Cart is a user's cart priced when it was read. Lines whose product could
+// not be fetched are listed in MissingProductIDs; they carry no price and
+// are left out of TotalPrice.
+type Cart struct {
+	UserID            int64      `json:"user_id"`
+	Items             []CartItem `json:"items"`
+	TotalPrice        int64      `json:"total_price"`
+	Partial           bool       `json:"partial"`
+	MissingProductIDs []int64    `json:"missing_product_ids,omitempty"`
+}
+
+// CartItem is a cart line. InStock reports whether the whole quantity is
+// available right now; it is not reserved until checkout.
+type CartItem struct {
+	ProductID int64  `json:"product_id"`
+	Quantity  int32  `json:"quantity"`
+	Name      string `json:"name,omitempty"`
+	Price     int64  `json:"price"`
+	Subtotal  int64  `json:"subtotal"`
+	InStock   bool   `json:"in_stock"`
+}
+
+func (s *processorService) GetCart(ctx context.Context, userID int64) (*Cart, error) {
+	items, err := s.carts.Items(ctx, userID)
+	if err != nil {
+		return nil, s.cartError(ctx, err)
+	}
+	return s.priceCart(ctx, userID, items), nil
+}
+
+// AddCartItem adds item to the cart, on top of the quantity already there,
+// once the product service confirms the product exists.
+func (s *processorService) AddCartItem(ctx context.Context, userID int64, item OrderItemInput) (*Cart, error) {
+	if item.ProductID <= 0 {
+		return nil, ErrInvalidProductID
+	}
+	if item.Quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	if _, err := s.GetProduct(ctx, item.ProductID); err != nil {
+		return nil, err
+	}
+
+	if err := s.carts.Add(ctx, userID, item.ProductID, item.Quantity); err != nil {
+		return nil, s.cartError(ctx, err)
+	}
+	return s.GetCart(ctx, userID)
+}
+
+// UpdateCartItem sets the quantity of a product already in the cart.
+func (s *processorService) UpdateCartItem(ctx context.Context, userID int64, item OrderItemInput) (*Cart, error) {
+	if item.Quantity <= 0 {
+		return nil, ErrInvalidQuantity
+	}
+
+	if err := s.carts.Set(ctx, userID, item.ProductID, item.Quantity); err != nil {
+		return nil, s.cartError(ctx, err)
+	}
+	return s.GetCart(ctx, userID)
+}
+
+func (s *processorService) RemoveCartItem(ctx context.Context, userID, productID int64) (*Cart, error) {
+	if err := s.carts.Remove(ctx, userID, productID); err != nil {
+		return nil, s.cartError(ctx, err)
+	}
+	return s.GetCart(ctx, userID)
+}
+
+func (s *processorService) ClearCart(ctx context.Context, userID int64) error {
+	if err := s.carts.Clear(ctx, userID); err != nil {
+		return s.cartError(ctx, err)
+	}
+	return nil
+}
+
+// CheckoutCart places an order for the cart through CreateOrder, which
+// prices and reserves every line again, and takes the ordered items out of
+// the cart once the order exists. The cart stays open meanwhile: anything
+// added during the checkout is kept for the next one. The backend calls are
+// keyed by the checkout id, and a checkout that may have placed its order
+// keeps its claim, so checking out again once the claim has expired resumes
+// that order rather than placing a second one.
+func (s *processorService) CheckoutCart(ctx context.Context, userID int64) (*Order, error) {
+	checkout, err := s.carts.BeginCheckout(ctx, userID)
+	if err != nil {
+		return nil, s.cartError(ctx, err)
+	}
+
+	// The claim must be dropped even if the client goes away.
+	storeCtx := context.WithoutCancel(ctx)
+	keepClaim := false
+	defer func() {
+		if keepClaim {
+			return
+		}
+		if err := s.carts.ReleaseCheckout(storeCtx, userID); err != nil {
+			s.log(ctx).Error("Error releasing cart checkout", slog.Int64("userID", userID), slog.String("error", err.Error()))
+		}
+	}()
+
+	if len(checkout.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	inputs := make([]OrderItemInput, 0, len(checkout.Items))
+	for _, item := range checkout.Items {
+		inputs = append(inputs, OrderItemInput{ProductID: item.ProductID, Quantity: item.Quantity})
+	}
+
+	operationID := "checkout/" + checkout.ID
+	order, err := s.createOrder(ctx, userID, inputs, orderAttempt{
+		operationID: operationID,
+		orderKey:    operationID + "/order",
+		resumed:     checkout.Resumed,
+	})
+	if err != nil {
+		if !orderRuledOut(err, checkout.Resumed) {
+			s.log(ctx).Error("Checkout outcome unknown, keeping the claim", slog.Int64("userID", userID), slog.String("checkoutID", checkout.ID))
+			keepClaim = true
+		}
+		return nil, err
+	}
+
+	// The order is placed; a cart left behind is reported but does not fail
+	// the checkout. The claim is kept, so the next checkout resumes this one
+	// and gets the same order back.
+	keepClaim = true
+	if err := s.carts.CompleteCheckout(storeCtx, userID); err != nil {
+		s.log(ctx).Error("Error clearing cart after checkout", slog.Int64("userID", userID), slog.Int64("orderID", order.ID), slog.String("error", err.Error()))
+	}
+
+	return order, nil
+}
+
+// orderRuledOut reports whether a failed checkout certainly placed no order,
+// so that its claim may be dropped. A fresh checkout leaves an order behind
+// only when its outcome is unknown; a resumed one may have placed it in an
+// earlier attempt, which only a settled order call rules out.
+func orderRuledOut(err error, resumed bool) bool {
+	if errors.Is(err, ErrOrderOutcomeUnknown) {
+		return false
+	}
+	var orderErr *OrderError
+	return !resumed || errors.As(err, &orderErr)
+}
+
+// priceCart looks up the current price and availability of every line, at
+// most productLookupConcurrency products at a time. Like CreateOrder it
+// bypasses the product cache, so the cart shows what checkout will charge.
+func (s *processorService) priceCart(ctx context.Context, userID int64, items []cart.Item) *Cart {
+	var (
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, productLookupConcurrency)
+		lines  = make([]CartItem, len(items))
+		failed = make([]bool, len(items))
+	)
+
+	for i, item := range items {
+		lines[i] = CartItem{ProductID: item.ProductID, Quantity: item.Quantity}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				failed[i] = true
+				return
+			}
+
+			if err := s.priceCartItem(ctx, &lines[i]); err != nil {
+				s.log(ctx).Warn("Error pricing cart item", slog.Int64("userID", userID), slog.Int64("productID", lines[i].ProductID), slog.String("error", err.Error()))
+				failed[i] = true
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	c := &Cart{UserID: userID, Items: lines}
+	for i, line := range lines {
+		if failed[i] {
+			c.Partial = true
+			c.MissingProductIDs = append(c.MissingProductIDs, line.ProductID)
+			continue
+		}
+		c.TotalPrice += line.Subtotal
+	}
+	return c
+}
+
+func (s *processorService) priceCartItem(ctx context.Context, line *CartItem) error {
+	ctx, cancel := context.WithTimeout(ctx, productLookupTimeout)
+	defer cancel()
+
+	details, err := s.productClient.GetProduct(ctx, line.ProductID)
+	if err != nil {
+		return err
+	}
+	if details == nil {
+		return fmt.Errorf("empty product details for id %d", line.ProductID)
+	}
+
+	inStock, err := s.productClient.CheckStock(ctx, line.ProductID, line.Quantity)
+	if err != nil {
+		return err
+	}
+
+	line.Name = details.GetName()
+	line.Price = details.GetPrice()
+	line.Subtotal = line.Price * int64(line.Quantity)
+	line.InStock = inStock
+	return nil
+}
+
+// cartError maps the errors of the cart store to those of the processor.
+func (s *processorService) cartError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, cart.ErrItemNotFound):
+		return ErrCartItemNotFound
+	case errors.Is(err, cart.ErrFull):
+		return ErrCartFull
+	case errors.Is(err, cart.ErrQuantityOverflow):
+		return ErrInvalidQuantity
+	case errors.Is(err, cart.ErrCheckoutClaimed):
+		return ErrCheckoutInProgress
+	}
+
+	s.log(ctx).Error("Error accessing cart store", slog.String("error", err.Error()))
+	return fmt.Errorf("cart store error: %w", err)
+}
@@ -0,0 +1,332 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/cart"
+	"ecomGateway/internal/grpc/interceptors"
+
+	order1 "github.com/KuranovNikita/ecomProto/gen/go/order"
+	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newCartServer() *mockProductServer {
+	return newMockProductServer(
+		&product1.ProductDetails{Id: 1, Name: "Laptop", Price: 120000, StockCount: 10},
+		&product1.ProductDetails{Id: 2, Name: "Mouse", Price: 2500, StockCount: 1},
+	)
+}
+
+// resumableCarts keeps the checkout of a single user and lets a test expire
+// its claim, so that the next checkout resumes it.
+type resumableCarts struct {
+	*cart.MemoryStore
+
+	mu       sync.Mutex
+	checkout *cart.Checkout
+	claimed  bool
+}
+
+func (s *resumableCarts) BeginCheckout(ctx context.Context, userID int64) (*cart.Checkout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.claimed {
+		return nil, cart.ErrCheckoutClaimed
+	}
+	if s.checkout == nil {
+		items, err := s.MemoryStore.Items(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		s.checkout = &cart.Checkout{ID: "c1", Items: items}
+	} else {
+		s.checkout.Resumed = true
+	}
+	s.claimed = true
+
+	checkout := *s.checkout
+	return &checkout, nil
+}
+
+func (s *resumableCarts) CompleteCheckout(ctx context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkout, s.claimed = nil, false
+	return s.MemoryStore.Clear(ctx, userID)
+}
+
+func (s *resumableCarts) ReleaseCheckout(ctx context.Context, userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkout, s.claimed = nil, false
+	return nil
+}
+
+func (s *resumableCarts) expireClaim() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.claimed = false
+}
+
+func TestProcessor_Cart_PricesLines(t *testing.T) {
+	productSrv := newCartServer()
+	proc, cleanup := setupTestProcessor(t, productSrv, &mockOrderServer{})
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 3})
+	require.NoError(t, err)
+	c, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, &Cart{
+		UserID: 7,
+		Items: []CartItem{
+			{ProductID: 1, Quantity: 2, Name: "Laptop", Price: 120000, Subtotal: 240000, InStock: true},
+			{ProductID: 2, Quantity: 3, Name: "Mouse", Price: 2500, Subtotal: 7500, InStock: false},
+		},
+		TotalPrice: 247500,
+	}, c)
+
+	productSrv.mu.Lock()
+	productSrv.products[1].Price = 110000
+	productSrv.mu.Unlock()
+
+	c, err = proc.UpdateCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	require.NoError(t, err)
+	assert.EqualValues(t, 220000+2500, c.TotalPrice, "lines are priced when the cart is read")
+	assert.True(t, c.Items[1].InStock)
+
+	c, err = proc.RemoveCartItem(ctx, 7, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []CartItem{{ProductID: 2, Quantity: 1, Name: "Mouse", Price: 2500, Subtotal: 2500, InStock: true}}, c.Items)
+
+	other, err := proc.GetCart(ctx, 8)
+	require.NoError(t, err)
+	assert.Empty(t, other.Items, "carts are per user")
+}
+
+func TestProcessor_Cart_MissingProduct(t *testing.T) {
+	productSrv := newCartServer()
+	proc, cleanup := setupTestProcessor(t, productSrv, &mockOrderServer{})
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	require.NoError(t, err)
+
+	productSrv.mu.Lock()
+	delete(productSrv.products, 2)
+	productSrv.mu.Unlock()
+
+	c, err := proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	assert.True(t, c.Partial)
+	assert.Equal(t, []int64{2}, c.MissingProductIDs)
+	assert.EqualValues(t, 120000, c.TotalPrice)
+	assert.Equal(t, CartItem{ProductID: 2, Quantity: 1}, c.Items[1])
+}
+
+func TestProcessor_Cart_InvalidChanges(t *testing.T) {
+	proc, cleanup := setupTestProcessorWithOptions(t, newCartServer(), &mockOrderServer{}, Options{
+		Carts: cart.NewMemoryStore(time.Hour, 1),
+	})
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 0, Quantity: 1})
+	assert.ErrorIs(t, err, ErrInvalidProductID)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 0})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 99, Quantity: 1})
+	st, ok := status.FromError(err)
+	require.True(t, ok, "unknown products are rejected by the product service")
+	assert.Equal(t, codes.NotFound, st.Code())
+
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	assert.ErrorIs(t, err, ErrCartFull)
+
+	_, err = proc.UpdateCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	assert.ErrorIs(t, err, ErrCartItemNotFound)
+	_, err = proc.UpdateCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: -1})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+	_, err = proc.RemoveCartItem(ctx, 7, 2)
+	assert.ErrorIs(t, err, ErrCartItemNotFound)
+}
+
+func TestProcessor_CheckoutCart(t *testing.T) {
+	productSrv := newCartServer()
+	orderSrv := &mockOrderServer{}
+	proc, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.CheckoutCart(ctx, 7)
+	assert.ErrorIs(t, err, ErrEmptyCart)
+
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	require.NoError(t, err)
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		assert.Equal(t, int64(7), req.UserId)
+		require.Len(t, req.Items, 2)
+		assert.Equal(t, int64(1), req.Items[0].ProductId)
+		assert.Equal(t, int32(2), req.Items[0].Quantity)
+		assert.Equal(t, int64(2), req.Items[1].ProductId)
+		return &order1.CreateOrderResponse{OrderId: 42, TotalPrice: 242500}, nil
+	}
+
+	order, err := proc.CheckoutCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), order.ID)
+	assert.EqualValues(t, 8, productSrv.stock(1))
+	assert.EqualValues(t, 0, productSrv.stock(2))
+
+	c, err := proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, c.Items, "the cart is emptied once the order exists")
+}
+
+func TestProcessor_CheckoutCart_FailureKeepsCart(t *testing.T) {
+	orderSrv := &mockOrderServer{}
+	proc, cleanup := setupTestProcessor(t, newCartServer(), orderSrv)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 2})
+	require.NoError(t, err)
+
+	_, err = proc.CheckoutCart(ctx, 7)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+
+	c, err := proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Len(t, c.Items, 1)
+}
+
+func TestProcessor_CheckoutCart_KeepsConcurrentChanges(t *testing.T) {
+	orderSrv := &mockOrderServer{}
+	proc, cleanup := setupTestProcessor(t, newCartServer(), orderSrv)
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 1})
+	require.NoError(t, err)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		close(entered)
+		<-release
+		return &order1.CreateOrderResponse{OrderId: 42, TotalPrice: 120000}, nil
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := proc.CheckoutCart(ctx, 7)
+		done <- err
+	}()
+	<-entered
+
+	_, err = proc.CheckoutCart(ctx, 7)
+	assert.ErrorIs(t, err, ErrCheckoutInProgress)
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 2, Quantity: 1})
+	require.NoError(t, err, "the cart stays open during checkout")
+	_, err = proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+
+	close(release)
+	require.NoError(t, <-done)
+
+	c, err := proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, c.Items, 2, "items added during checkout survive it")
+	assert.Equal(t, int64(1), c.Items[0].ProductID)
+	assert.EqualValues(t, 2, c.Items[0].Quantity, "only the ordered quantity is taken out")
+	assert.Equal(t, int64(2), c.Items[1].ProductID)
+	assert.EqualValues(t, 1, c.Items[1].Quantity)
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		return &order1.CreateOrderResponse{OrderId: 43}, nil
+	}
+	_, err = proc.CheckoutCart(ctx, 7)
+	assert.NoError(t, err, "the claim is dropped after checkout")
+}
+
+func TestProcessor_CheckoutCart_UnknownOutcomeIsResumed(t *testing.T) {
+	productSrv := newCartServer()
+	orderSrv := &mockOrderServer{}
+	carts := &resumableCarts{MemoryStore: cart.NewMemoryStore(time.Hour, 0)}
+	proc, cleanup := setupTestProcessorWithOptions(t, productSrv, orderSrv, Options{Carts: carts})
+	defer cleanup()
+	ctx := context.Background()
+
+	_, err := proc.AddCartItem(ctx, 7, OrderItemInput{ProductID: 1, Quantity: 2})
+	require.NoError(t, err)
+
+	// The order service commits the order, but its answers are lost until
+	// lost is cleared; a repeat of the key then returns the stored order.
+	var mu sync.Mutex
+	lost := true
+	orders := make(map[string]int64)
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := md.Get(interceptors.IdempotencyKeyMetadata)[0]
+
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := orders[key]; !ok {
+			orders[key] = int64(len(orders) + 42)
+		}
+		if lost {
+			return nil, status.Error(codes.Unavailable, "connection reset")
+		}
+		return &order1.CreateOrderResponse{OrderId: orders[key]}, nil
+	}
+
+	_, err = proc.CheckoutCart(ctx, 7)
+	require.ErrorIs(t, err, ErrOrderOutcomeUnknown)
+	assert.EqualValues(t, 8, productSrv.stock(1), "the stock of an order that may exist stays reserved")
+
+	_, err = proc.CheckoutCart(ctx, 7)
+	assert.ErrorIs(t, err, ErrCheckoutInProgress, "the claim is kept")
+	c, err := proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Len(t, c.Items, 1)
+
+	mu.Lock()
+	lost = false
+	mu.Unlock()
+	carts.expireClaim()
+
+	order, err := proc.CheckoutCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), order.ID, "the resumed checkout gets the order placed before")
+	assert.Len(t, orders, 1, "a single order is created")
+	assert.EqualValues(t, 8, productSrv.stock(1), "its stock is reserved once")
+
+	c, err = proc.GetCart(ctx, 7)
+	require.NoError(t, err)
+	assert.Empty(t, c.Items)
+}
@@ -2,52 +2,230 @@ package processor
 
 import (
 	"context"
+	"crypto/rand"
+	"ecomGateway/internal/cache"
+	"ecomGateway/internal/cart"
+	"ecomGateway/internal/grpc/interceptors"
 	ordergrpc "ecomGateway/internal/grpc/order"
 	productgrpc "ecomGateway/internal/grpc/product"
 	usergrpc "ecomGateway/internal/grpc/user"
+	"ecomGateway/internal/idempotency"
+	requestid "ecomGateway/internal/lib/request_id"
+	"ecomGateway/internal/metrics"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	order1 "github.com/KuranovNikita/ecomProto/gen/go/order"
+	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Processor interface {
 	RegisterUser(ctx context.Context, email, password, login string) (int64, error)
 	LoginUser(ctx context.Context, login, password string) (string, error)
-	// ListProducts(ctx context.Context, filter, id string) ([]Product, error)
-	// CreateOrder(ctx context.Context, userID int64, items []OrderItemHTTP) (*Order, error)
-	// ListUserOrders(ctx context.Context, userID int64) ([]OrderDTO, error)
+	GetUser(ctx context.Context, userID int64) (*User, error)
+	ListProducts(ctx context.Context, filter string) ([]Product, error)
+	ListProductsPage(ctx context.Context, query ProductQuery) (*ProductPage, error)
+	GetProduct(ctx context.Context, id int64) (*Product, error)
+	CreateOrder(ctx context.Context, userID int64, items []OrderItemInput) (*Order, error)
+	ListUserOrders(ctx context.Context, userID int64) ([]Order, error)
+	GetOrder(ctx context.Context, userID, orderID int64) (*Order, error)
+	GetOrderView(ctx context.Context, userID, orderID int64) (*OrderView, error)
+	GetCart(ctx context.Context, userID int64) (*Cart, error)
+	AddCartItem(ctx context.Context, userID int64, item OrderItemInput) (*Cart, error)
+	UpdateCartItem(ctx context.Context, userID int64, item OrderItemInput) (*Cart, error)
+	RemoveCartItem(ctx context.Context, userID, productID int64) (*Cart, error)
+	ClearCart(ctx context.Context, userID int64) error
+	CheckoutCart(ctx context.Context, userID int64) (*Order, error)
 }
 
 type processorService struct {
 	userClient    usergrpc.Client
 	orderClient   ordergrpc.Client
 	productClient productgrpc.Client
+	logger        *slog.Logger
+
+	products     *cache.Cache[int64, *product1.ProductDetails]
+	productLists *cache.Cache[string, []*product1.ProductDetails]
+
+	carts cart.Store
+}
+
+// Options tunes the processor beyond its backend clients.
+type Options struct {
+	ProductCache CacheOptions
+	// Carts keeps the shopping carts; nil keeps them in memory with neither
+	// expiry nor a size limit.
+	Carts cart.Store
+}
+
+// CacheOptions bounds a cache by entry count and age; a zero Size or TTL
+// disables it.
+type CacheOptions struct {
+	Size int
+	TTL  time.Duration
+}
+
+func (o CacheOptions) Enabled() bool {
+	return o.Size > 0 && o.TTL > 0
+}
+
+type User struct {
+	ID    int64  `json:"user_id"`
+	Login string `json:"login"`
+	Email string `json:"email"`
 }
 
 type Product struct {
-	Id          int64
-	Name        string
-	Description string
-	Price       int64
-	StockCount  int32
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Price       int64  `json:"price"`
+	StockCount  int32  `json:"stock_count"`
+}
+
+type OrderItemInput struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int32 `json:"quantity"`
+}
+
+type OrderItem struct {
+	ProductID int64 `json:"product_id"`
+	Quantity  int32 `json:"quantity"`
+	Price     int64 `json:"price"`
 }
 
+type Order struct {
+	ID         int64       `json:"order_id"`
+	UserID     int64       `json:"user_id"`
+	Items      []OrderItem `json:"items"`
+	TotalPrice int64       `json:"total_price"`
+	Status     string      `json:"status,omitempty"`
+}
+
+// OrderView is an order with product data embedded into every item. Partial
+// is set when some products could not be fetched in time; their items keep
+// only the data stored with the order.
+type OrderView struct {
+	ID                int64           `json:"order_id"`
+	UserID            int64           `json:"user_id"`
+	Items             []OrderViewItem `json:"items"`
+	TotalPrice        int64           `json:"total_price"`
+	Status            string          `json:"status,omitempty"`
+	Partial           bool            `json:"partial"`
+	MissingProductIDs []int64         `json:"missing_product_ids,omitempty"`
+}
+
+type OrderViewItem struct {
+	OrderItem
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// OrderError is returned by CreateOrder when the order could not be placed
+// after stock had already been reserved. It carries the outcome of the
+// compensating stock releases so callers can report it. UnsettledIDs lists
+// the products whose stock was left reserved because it could not be told
+// whether the reservation, or the order it was for, took effect; releasing
+// them could hand out stock that is not there.
+type OrderError struct {
+	Err                error
+	Compensated        bool
+	FailedReleaseIDs   []int64
+	CompensationErrors []error
+	UnsettledIDs       []int64
+}
+
+func (e *OrderError) Error() string {
+	if e.Compensated {
+		return fmt.Sprintf("%v (reserved stock released)", e.Err)
+	}
+
+	msgs := make([]string, 0, len(e.CompensationErrors)+len(e.UnsettledIDs))
+	for _, err := range e.CompensationErrors {
+		msgs = append(msgs, err.Error())
+	}
+	for _, id := range e.UnsettledIDs {
+		msgs = append(msgs, fmt.Sprintf("product %d: left reserved", id))
+	}
+	return fmt.Sprintf("%v (failed to release reserved stock: %s)", e.Err, strings.Join(msgs, "; "))
+}
+
+func (e *OrderError) Unwrap() error {
+	return e.Err
+}
+
+const (
+	// compensationTimeout bounds the calls made after a failure to settle
+	// its outcome or undo it.
+	compensationTimeout = 5 * time.Second
+
+	// Limits for the product lookups made while building an OrderView.
+	productLookupConcurrency = 8
+	productLookupTimeout     = time.Second
+)
+
+var (
+	ErrEmptyOrder        = errors.New("order must contain at least one item")
+	ErrInvalidQuantity   = errors.New("item quantity must be positive")
+	ErrInvalidProductID  = errors.New("item product id must be positive")
+	ErrInsufficientStock = errors.New("insufficient stock")
+	ErrOrderNotFound     = errors.New("order not found")
+	// ErrOrderOutcomeUnknown means the order service failed in a way that
+	// leaves open whether the order was created; its stock stays reserved.
+	ErrOrderOutcomeUnknown = errors.New("order outcome is unknown")
+)
+
 func NewProcessorService(
 	userClient usergrpc.Client,
 	orderClient ordergrpc.Client,
 	productClient productgrpc.Client,
+	logger *slog.Logger,
+	opts Options,
 ) Processor {
-	return &processorService{
+	s := &processorService{
 		userClient:    userClient,
 		productClient: productClient,
 		orderClient:   orderClient,
+		logger:        logger,
+		carts:         opts.Carts,
+	}
+
+	if s.carts == nil {
+		s.carts = cart.NewMemoryStore(0, 0)
+	}
+
+	if opts.ProductCache.Enabled() {
+		s.products = cache.New[int64, *product1.ProductDetails]("products", opts.ProductCache.Size, opts.ProductCache.TTL)
+		s.productLists = cache.New[string, []*product1.ProductDetails]("product_lists", opts.ProductCache.Size, opts.ProductCache.TTL)
+		metrics.ObserveCache(s.products)
+		metrics.ObserveCache(s.productLists)
+	}
+
+	return s
+}
+
+// log returns the service logger annotated with the request ID carried by ctx.
+func (s *processorService) log(ctx context.Context) *slog.Logger {
+	if id := requestid.FromContext(ctx); id != "" {
+		return s.logger.With(slog.String("request_id", id))
 	}
+	return s.logger
 }
 
 func (s *processorService) RegisterUser(ctx context.Context, email, password, login string) (int64, error) {
+	ctx = interceptors.WithIdempotencyKey(ctx, clientKey(ctx, newOperationID())+"/register")
 	resp, err := s.userClient.Register(ctx, email, login, password)
 
 	if err != nil {
-		log.Printf("Error registering user: %v", err)
+		s.log(ctx).Error("Error registering user", slog.String("error", err.Error()))
 		return 0, fmt.Errorf("user service error: %w", err)
 	}
 	return resp, nil
@@ -56,13 +234,442 @@ func (s *processorService) RegisterUser(ctx context.Context, email, password, lo
 func (s *processorService) LoginUser(ctx context.Context, login, password string) (string, error) {
 	resp, err := s.userClient.Login(ctx, login, password)
 	if err != nil {
-		log.Printf("Error logging user : %v", err)
+		s.log(ctx).Error("Error logging user in", slog.String("error", err.Error()))
 		return "", fmt.Errorf("user service error: %w", err)
 	}
 
 	return resp, nil
 }
 
-// func (s *processorService) ListProducts(ctx context.Context, filter, id string) ([]Product, error) {
+func (s *processorService) GetUser(ctx context.Context, userID int64) (*User, error) {
+	resp, err := s.userClient.GetUser(ctx, userID)
+	if err != nil {
+		s.log(ctx).Error("Error getting user", slog.Int64("userID", userID), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("user service error: %w", err)
+	}
+
+	return &User{
+		ID:    resp.GetUserId(),
+		Login: resp.GetLogin(),
+		Email: resp.GetEmail(),
+	}, nil
+}
+
+func (s *processorService) ListProducts(ctx context.Context, filter string) ([]Product, error) {
+	resp, err := s.listProducts(ctx, filter)
+	if err != nil {
+		s.log(ctx).Error("Error listing products", slog.String("error", err.Error()))
+		return nil, fmt.Errorf("product service error: %w", err)
+	}
+
+	products := make([]Product, 0, len(resp))
+	for _, details := range resp {
+		if details == nil {
+			continue
+		}
+		products = append(products, productFromDetails(details))
+	}
+
+	return products, nil
+}
+
+func (s *processorService) GetProduct(ctx context.Context, id int64) (*Product, error) {
+	resp, err := s.getProduct(ctx, id)
+	if err != nil {
+		s.log(ctx).Error("Error getting product", slog.Int64("productID", id), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("product service error: %w", err)
+	}
+
+	if resp == nil {
+		return nil, fmt.Errorf("product service error: empty product details for id %d", id)
+	}
+
+	product := productFromDetails(resp)
+	return &product, nil
+}
+
+func productFromDetails(details *product1.ProductDetails) Product {
+	return Product{
+		Id:          details.GetId(),
+		Name:        details.GetName(),
+		Description: details.GetDescription(),
+		Price:       details.GetPrice(),
+		StockCount:  details.GetStockCount(),
+	}
+}
+
+// CreateOrder prices the requested items with the product service, reserves
+// the stock and only then creates the order. Prices sent by the client are
+// never trusted: every item is priced from GetProduct, bypassing the product
+// cache. Each stock update and the order itself carry an idempotency key, so
+// the clients may retry them, and a call that fails without saying whether
+// it took effect is sent once more under its key to find out. Stock is only
+// released when the call that needed it certainly did not go through.
+func (s *processorService) CreateOrder(ctx context.Context, userID int64, items []OrderItemInput) (*Order, error) {
+	operationID := newOperationID()
+	return s.createOrder(ctx, userID, items, orderAttempt{
+		operationID: operationID,
+		orderKey:    clientKey(ctx, operationID) + "/order",
+	})
+}
+
+// orderAttempt keys the backend calls of one attempt to place an order.
+// Repeating an attempt with the same keys settles the earlier one instead of
+// placing the order again.
+type orderAttempt struct {
+	operationID string
+	orderKey    string
+	// resumed is set when an earlier run of the attempt may have reserved
+	// the stock already, which the availability check would then miss.
+	resumed bool
+}
+
+func (s *processorService) createOrder(ctx context.Context, userID int64, items []OrderItemInput, attempt orderAttempt) (*Order, error) {
+	merged, err := mergeOrderItems(items)
+	if err != nil {
+		return nil, err
+	}
+
+	priced := make([]OrderItem, 0, len(merged))
+	for _, item := range merged {
+		details, err := s.productClient.GetProduct(ctx, item.ProductID)
+		if err != nil {
+			s.log(ctx).Error("Error getting product for order", slog.Int64("productID", item.ProductID), slog.String("error", err.Error()))
+			return nil, fmt.Errorf("product service error: %w", err)
+		}
+		if details == nil {
+			return nil, fmt.Errorf("product service error: empty product details for id %d", item.ProductID)
+		}
+
+		if !attempt.resumed {
+			available, err := s.productClient.CheckStock(ctx, item.ProductID, item.Quantity)
+			if err != nil {
+				s.log(ctx).Error("Error checking stock", slog.Int64("productID", item.ProductID), slog.String("error", err.Error()))
+				return nil, fmt.Errorf("product service error: %w", err)
+			}
+			if !available {
+				return nil, fmt.Errorf("%w: product %d", ErrInsufficientStock, item.ProductID)
+			}
+		}
+
+		priced = append(priced, OrderItem{
+			ProductID: item.ProductID,
+			Quantity:  item.Quantity,
+			Price:     details.GetPrice(),
+		})
+	}
+
+	reserved := make([]OrderItem, 0, len(priced))
+	for _, item := range priced {
+		key := fmt.Sprintf("%s/reserve/%d", attempt.operationID, item.ProductID)
+		err := s.settle(ctx, key, func(ctx context.Context) error {
+			return s.productClient.UpdateStock(ctx, item.ProductID, -item.Quantity)
+		})
+		s.invalidateProduct(item.ProductID)
+		if err != nil {
+			s.log(ctx).Error("Error reserving stock", slog.Int64("productID", item.ProductID), slog.String("error", err.Error()))
+			var unsettled []int64
+			if !callRejected(err) {
+				s.log(ctx).Error("Stock reservation outcome unknown, leaving it in place", slog.Int64("productID", item.ProductID), slog.Int("quantity", int(item.Quantity)))
+				unsettled = append(unsettled, item.ProductID)
+			}
+			return nil, s.compensate(ctx, attempt.operationID, reserved, unsettled, fmt.Errorf("product service error: %w", err))
+		}
+		reserved = append(reserved, item)
+	}
 
-// }
+	orderItems := make([]*order1.OrderItem, 0, len(priced))
+	for _, item := range priced {
+		orderItems = append(orderItems, ordergrpc.NewOrderItem(item.ProductID, item.Quantity, item.Price))
+	}
+
+	var orderID, totalPrice int64
+	err = s.settle(ctx, attempt.orderKey, func(ctx context.Context) error {
+		var err error
+		orderID, totalPrice, err = s.orderClient.CreateOrder(ctx, userID, orderItems)
+		return err
+	})
+	if err != nil {
+		s.log(ctx).Error("Error creating order", slog.Int64("userID", userID), slog.String("error", err.Error()))
+		if !callRejected(err) {
+			// The order may exist, so its stock must stay reserved.
+			s.log(ctx).Error("Order outcome unknown, keeping the reserved stock", slog.Int64("userID", userID))
+			idempotency.Keep(ctx)
+			orderErr := &OrderError{Err: fmt.Errorf("%w: order service error: %w", ErrOrderOutcomeUnknown, err)}
+			for _, item := range reserved {
+				orderErr.UnsettledIDs = append(orderErr.UnsettledIDs, item.ProductID)
+			}
+			return nil, orderErr
+		}
+		return nil, s.compensate(ctx, attempt.operationID, reserved, nil, fmt.Errorf("order service error: %w", err))
+	}
+
+	return &Order{
+		ID:         orderID,
+		UserID:     userID,
+		Items:      priced,
+		TotalPrice: totalPrice,
+	}, nil
+}
+
+func (s *processorService) ListUserOrders(ctx context.Context, userID int64) ([]Order, error) {
+	resp, err := s.orderClient.ListUserOrders(ctx, userID)
+	if err != nil {
+		s.log(ctx).Error("Error listing orders", slog.Int64("userID", userID), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("order service error: %w", err)
+	}
+
+	orders := make([]Order, 0, len(resp))
+	for _, details := range resp {
+		if details == nil || details.GetUserId() != userID {
+			continue
+		}
+		orders = append(orders, orderFromDetails(details))
+	}
+
+	return orders, nil
+}
+
+// GetOrder returns the order only if it belongs to userID. An order owned by
+// someone else is reported as ErrOrderNotFound so ids cannot be enumerated.
+func (s *processorService) GetOrder(ctx context.Context, userID, orderID int64) (*Order, error) {
+	resp, err := s.orderClient.GetOrder(ctx, orderID)
+	if err != nil {
+		s.log(ctx).Error("Error getting order", slog.Int64("orderID", orderID), slog.String("error", err.Error()))
+		return nil, fmt.Errorf("order service error: %w", err)
+	}
+
+	if resp == nil || resp.GetUserId() != userID {
+		return nil, ErrOrderNotFound
+	}
+
+	order := orderFromDetails(resp)
+	return &order, nil
+}
+
+func (s *processorService) GetOrderView(ctx context.Context, userID, orderID int64) (*OrderView, error) {
+	order, err := s.GetOrder(ctx, userID, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(order.Items))
+	seen := make(map[int64]struct{}, len(order.Items))
+	for _, item := range order.Items {
+		if _, ok := seen[item.ProductID]; ok {
+			continue
+		}
+		seen[item.ProductID] = struct{}{}
+		ids = append(ids, item.ProductID)
+	}
+
+	products := s.lookupProducts(ctx, ids)
+
+	view := &OrderView{
+		ID:         order.ID,
+		UserID:     order.UserID,
+		Items:      make([]OrderViewItem, 0, len(order.Items)),
+		TotalPrice: order.TotalPrice,
+		Status:     order.Status,
+	}
+	for _, item := range order.Items {
+		viewItem := OrderViewItem{OrderItem: item}
+		if product, ok := products[item.ProductID]; ok {
+			viewItem.Name = product.GetName()
+			viewItem.Description = product.GetDescription()
+		}
+		view.Items = append(view.Items, viewItem)
+	}
+	for _, id := range ids {
+		if _, ok := products[id]; !ok {
+			view.Partial = true
+			view.MissingProductIDs = append(view.MissingProductIDs, id)
+		}
+	}
+
+	return view, nil
+}
+
+// lookupProducts fetches the given products concurrently, at most
+// productLookupConcurrency at a time. Products that fail or time out are
+// simply absent from the result.
+func (s *processorService) lookupProducts(ctx context.Context, ids []int64) map[int64]*product1.ProductDetails {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		products = make(map[int64]*product1.ProductDetails, len(ids))
+		sem      = make(chan struct{}, productLookupConcurrency)
+	)
+
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				return
+			}
+
+			lookupCtx, cancel := context.WithTimeout(ctx, productLookupTimeout)
+			defer cancel()
+
+			details, err := s.getProduct(lookupCtx, id)
+			if err != nil || details == nil {
+				s.log(ctx).Warn("Error getting product for order view", slog.Int64("productID", id), slog.Any("error", err))
+				return
+			}
+
+			mu.Lock()
+			products[id] = details
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+
+	return products
+}
+
+func orderFromDetails(details *order1.OrderDetails) Order {
+	items := make([]OrderItem, 0, len(details.GetItems()))
+	for _, item := range details.GetItems() {
+		items = append(items, OrderItem{
+			ProductID: item.GetProductId(),
+			Quantity:  item.GetQuantity(),
+			Price:     item.GetPrice(),
+		})
+	}
+
+	return Order{
+		ID:         details.GetOrderId(),
+		UserID:     details.GetUserId(),
+		Items:      items,
+		TotalPrice: details.GetTotalPrice(),
+		Status:     details.GetStatus(),
+	}
+}
+
+// compensate releases the stock reserved so far in reverse order. It runs on
+// a context detached from the request so a cancelled client does not leave
+// the reservation behind. The unsettled products are reported but kept.
+func (s *processorService) compensate(ctx context.Context, operationID string, reserved []OrderItem, unsettled []int64, cause error) error {
+	if len(reserved) == 0 && len(unsettled) == 0 {
+		return cause
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	orderErr := &OrderError{Err: cause, UnsettledIDs: unsettled}
+	for i := len(reserved) - 1; i >= 0; i-- {
+		item := reserved[i]
+		key := fmt.Sprintf("%s/release/%d", operationID, item.ProductID)
+		err := s.settle(ctx, key, func(ctx context.Context) error {
+			return s.productClient.UpdateStock(ctx, item.ProductID, item.Quantity)
+		})
+		s.invalidateProduct(item.ProductID)
+		if err != nil {
+			s.log(ctx).Error("Compensation failed: could not release stock", slog.Int64("productID", item.ProductID), slog.Int("quantity", int(item.Quantity)), slog.String("error", err.Error()))
+			orderErr.FailedReleaseIDs = append(orderErr.FailedReleaseIDs, item.ProductID)
+			orderErr.CompensationErrors = append(orderErr.CompensationErrors, fmt.Errorf("product %d: %w", item.ProductID, err))
+			continue
+		}
+		s.log(ctx).Info("Compensation: released stock", slog.Int64("productID", item.ProductID), slog.Int("quantity", int(item.Quantity)))
+	}
+	orderErr.Compensated = len(orderErr.FailedReleaseIDs) == 0 && len(orderErr.UnsettledIDs) == 0
+
+	return orderErr
+}
+
+// settle makes a mutating backend call under the idempotency key. If the
+// call fails without saying whether it was applied, it is sent once more
+// under the same key, on a context detached from the request: the backend
+// answers a repeat from what the first call did, so the outcome is known
+// unless the repeat fails in the same way.
+func (s *processorService) settle(ctx context.Context, key string, call func(ctx context.Context) error) error {
+	err := call(interceptors.WithIdempotencyKey(ctx, key))
+	if err == nil || callRejected(err) {
+		return err
+	}
+
+	s.log(ctx).Warn("Backend call outcome unknown, sending it again", slog.String("key", key), slog.String("error", err.Error()))
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	return call(interceptors.WithIdempotencyKey(ctx, key))
+}
+
+// newOperationID names one processor operation in the idempotency keys of
+// its backend calls; a retried call carries the same key as the first
+// attempt.
+func newOperationID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// clientKey prefers the client's Idempotency-Key, so that a repeated request
+// cannot register a user or place an order twice. Stock reservations always
+// use the operation id instead, since a retry under the client's key only
+// runs again when no order came of the earlier attempt: its stock was then
+// released, and the retry must reserve anew. An attempt that may have placed
+// the order keeps the client's key with idempotency.Keep, so its retries are
+// answered from the stored response and never reserve again.
+func clientKey(ctx context.Context, operationID string) string {
+	if key := idempotency.KeyFromContext(ctx); key != "" {
+		return key
+	}
+	return operationID
+}
+
+// callRejected reports whether a failed mutating call certainly had no
+// effect: either the backend refused it or an open breaker never sent it.
+// Timeouts and other failures may have been applied.
+func callRejected(err error) bool {
+	if interceptors.IsBreakerOpen(err) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound, codes.OutOfRange,
+		codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented:
+		return true
+	}
+	return false
+}
+
+// mergeOrderItems validates the requested items and folds repeated product
+// ids into a single line, keeping the order in which they first appeared.
+func mergeOrderItems(items []OrderItemInput) ([]OrderItemInput, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+
+	merged := make([]OrderItemInput, 0, len(items))
+	index := make(map[int64]int, len(items))
+	for _, item := range items {
+		if item.ProductID <= 0 {
+			return nil, ErrInvalidProductID
+		}
+		if item.Quantity <= 0 {
+			return nil, ErrInvalidQuantity
+		}
+
+		i, ok := index[item.ProductID]
+		if !ok {
+			index[item.ProductID] = len(merged)
+			merged = append(merged, item)
+			continue
+		}
+
+		if int64(merged[i].Quantity)+int64(item.Quantity) > math.MaxInt32 {
+			return nil, ErrInvalidQuantity
+		}
+		merged[i].Quantity += item.Quantity
+	}
+
+	return merged, nil
+}
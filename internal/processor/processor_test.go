@@ -0,0 +1,636 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"ecomGateway/internal/grpc/interceptors"
+	ordergrpc "ecomGateway/internal/grpc/order"
+	productgrpc "ecomGateway/internal/grpc/product"
+	usergrpc "ecomGateway/internal/grpc/user"
+	"ecomGateway/internal/idempotency"
+	requestid "ecomGateway/internal/lib/request_id"
+
+	order1 "github.com/KuranovNikita/ecomProto/gen/go/order"
+	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type mockProductServer struct {
+	product1.UnimplementedProductServiceServer
+
+	mu              sync.Mutex
+	products        map[int64]*product1.ProductDetails
+	updates         []*product1.UpdateStockRequest
+	appliedKeys     map[string]bool
+	getProductCalls map[int64]int
+
+	GetProductDelay time.Duration
+
+	UpdateStockFunc func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error)
+}
+
+func newMockProductServer(products ...*product1.ProductDetails) *mockProductServer {
+	s := &mockProductServer{
+		products:        make(map[int64]*product1.ProductDetails),
+		appliedKeys:     make(map[string]bool),
+		getProductCalls: make(map[int64]int),
+	}
+	for _, p := range products {
+		s.products[p.Id] = p
+	}
+	return s
+}
+
+func (s *mockProductServer) GetProduct(ctx context.Context, req *product1.GetProductRequest) (*product1.GetProductResponse, error) {
+	if s.GetProductDelay > 0 {
+		select {
+		case <-time.After(s.GetProductDelay):
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.getProductCalls[req.ProductId]++
+
+	p, ok := s.products[req.ProductId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "product %d not found", req.ProductId)
+	}
+	return &product1.GetProductResponse{ProductDetails: &product1.ProductDetails{
+		Id:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		Price:       p.Price,
+		StockCount:  p.StockCount,
+	}}, nil
+}
+
+func (s *mockProductServer) ListProducts(ctx context.Context, req *product1.ListProductsRequest) (*product1.ListProductsResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resp := &product1.ListProductsResponse{}
+	for _, p := range s.products {
+		resp.Products = append(resp.Products, &product1.ProductDetails{
+			Id:          p.Id,
+			Name:        p.Name,
+			Description: p.Description,
+			Price:       p.Price,
+			StockCount:  p.StockCount,
+		})
+	}
+	return resp, nil
+}
+
+func (s *mockProductServer) CheckStock(ctx context.Context, req *product1.CheckStockRequest) (*product1.CheckStockResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.products[req.ProductId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "product %d not found", req.ProductId)
+	}
+	return &product1.CheckStockResponse{IsAvailable: p.StockCount >= req.Quantity}, nil
+}
+
+func (s *mockProductServer) UpdateStock(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+	if s.UpdateStockFunc != nil {
+		if resp, err := s.UpdateStockFunc(ctx, req); resp != nil || err != nil {
+			return resp, err
+		}
+	}
+	return s.applyStockUpdate(ctx, req)
+}
+
+// applyStockUpdate changes the stock once per idempotency key and answers
+// repeats of a key with success, as the product service does.
+func (s *mockProductServer) applyStockUpdate(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(interceptors.IdempotencyKeyMetadata)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(keys) > 0 && s.appliedKeys[keys[0]] {
+		return &emptypb.Empty{}, nil
+	}
+
+	p, ok := s.products[req.ProductId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "product %d not found", req.ProductId)
+	}
+	p.StockCount += req.QuantityChange
+	s.updates = append(s.updates, req)
+	if len(keys) > 0 {
+		s.appliedKeys[keys[0]] = true
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *mockProductServer) stock(id int64) int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.products[id].StockCount
+}
+
+type mockOrderServer struct {
+	order1.UnimplementedOrderServiceServer
+
+	CreateOrderFunc    func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error)
+	GetOrderFunc       func(ctx context.Context, req *order1.GetOrderRequest) (*order1.GetOrderResponse, error)
+	ListUserOrdersFunc func(ctx context.Context, req *order1.ListUserOrdersRequest) (*order1.ListUserOrdersResponse, error)
+}
+
+func (s *mockOrderServer) CreateOrder(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+	if s.CreateOrderFunc != nil {
+		return s.CreateOrderFunc(ctx, req)
+	}
+	return nil, status.Errorf(codes.Unimplemented, "method CreateOrder not implemented")
+}
+
+func (s *mockOrderServer) GetOrder(ctx context.Context, req *order1.GetOrderRequest) (*order1.GetOrderResponse, error) {
+	if s.GetOrderFunc != nil {
+		return s.GetOrderFunc(ctx, req)
+	}
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrder not implemented")
+}
+
+func (s *mockOrderServer) ListUserOrders(ctx context.Context, req *order1.ListUserOrdersRequest) (*order1.ListUserOrdersResponse, error) {
+	if s.ListUserOrdersFunc != nil {
+		return s.ListUserOrdersFunc(ctx, req)
+	}
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserOrders not implemented")
+}
+
+func setupTestProcessor(t *testing.T, productSrv *mockProductServer, orderSrv *mockOrderServer) (Processor, func()) {
+	t.Helper()
+	return setupTestProcessorWithOptions(t, productSrv, orderSrv, Options{})
+}
+
+func setupTestProcessorWithOptions(t *testing.T, productSrv *mockProductServer, orderSrv *mockOrderServer, opts Options) (Processor, func()) {
+	t.Helper()
+
+	bufSize := 1024 * 1024
+	lis := bufconn.Listen(bufSize)
+
+	grpcServer := grpc.NewServer()
+	product1.RegisterProductServiceServer(grpcServer, productSrv)
+	order1.RegisterOrderServiceServer(grpcServer, orderSrv)
+
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Logf("gRPC server error: %v", err)
+		}
+	}()
+
+	bufDialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	dialOpts := []grpc.DialOption{
+		grpc.WithContextDialer(bufDialer),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+
+	userClient, err := usergrpc.New(slog.Default(), "passthrough:///bufnet", 1*time.Second, interceptors.RetryPolicy{}, interceptors.BreakerPolicy{}, dialOpts...)
+	require.NoError(t, err)
+	orderClient, err := ordergrpc.New(slog.Default(), "passthrough:///bufnet", 1*time.Second, interceptors.RetryPolicy{}, interceptors.BreakerPolicy{}, dialOpts...)
+	require.NoError(t, err)
+	productClient, err := productgrpc.New(slog.Default(), "passthrough:///bufnet", 1*time.Second, interceptors.RetryPolicy{}, interceptors.BreakerPolicy{}, dialOpts...)
+	require.NoError(t, err)
+
+	cleanup := func() {
+		grpcServer.GracefulStop()
+		lis.Close()
+	}
+
+	return NewProcessorService(*userClient, *orderClient, *productClient, slog.Default(), opts), cleanup
+}
+
+func testProducts() []*product1.ProductDetails {
+	return []*product1.ProductDetails{
+		{Id: 1, Name: "Laptop", Price: 120000, StockCount: 10},
+		{Id: 2, Name: "Mouse", Price: 2500, StockCount: 5},
+	}
+}
+
+func TestProcessor_CreateOrder_Success(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		assert.Equal(t, int64(7), req.UserId)
+		require.Len(t, req.Items, 2)
+		assert.Equal(t, int64(120000), req.Items[0].Price)
+		assert.Equal(t, int32(2), req.Items[0].Quantity)
+		assert.Equal(t, int64(2500), req.Items[1].Price)
+		return &order1.CreateOrderResponse{OrderId: 42, TotalPrice: 242500}, nil
+	}
+
+	order, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+		{ProductID: 1, Quantity: 1},
+		{ProductID: 2, Quantity: 1},
+		{ProductID: 1, Quantity: 1},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), order.ID)
+	assert.Equal(t, int64(242500), order.TotalPrice)
+	assert.Equal(t, int32(8), productSrv.stock(1))
+	assert.Equal(t, int32(4), productSrv.stock(2))
+}
+
+func TestProcessor_CreateOrder_InsufficientStock(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	p, cleanup := setupTestProcessor(t, productSrv, &mockOrderServer{})
+	defer cleanup()
+
+	_, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{{ProductID: 2, Quantity: 6}})
+
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	assert.Empty(t, productSrv.updates)
+}
+
+func TestProcessor_CreateOrder_OrderServiceFailureRestoresStock(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		return nil, status.Error(codes.FailedPrecondition, "user account is blocked")
+	}
+
+	_, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+		{ProductID: 1, Quantity: 3},
+		{ProductID: 2, Quantity: 2},
+	})
+
+	require.Error(t, err)
+	var orderErr *OrderError
+	require.True(t, errors.As(err, &orderErr))
+	assert.True(t, orderErr.Compensated)
+	assert.Empty(t, orderErr.FailedReleaseIDs)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	assert.Equal(t, int32(10), productSrv.stock(1))
+	assert.Equal(t, int32(5), productSrv.stock(2))
+
+	require.Len(t, productSrv.updates, 4)
+	assert.Equal(t, int64(2), productSrv.updates[2].ProductId, "compensation must run in reverse order")
+	assert.Equal(t, int32(2), productSrv.updates[2].QuantityChange)
+	assert.Equal(t, int64(1), productSrv.updates[3].ProductId)
+	assert.Equal(t, int32(3), productSrv.updates[3].QuantityChange)
+}
+
+func TestProcessor_CreateOrder_ReservationFailureReleasesPreviousItems(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	p, cleanup := setupTestProcessor(t, productSrv, &mockOrderServer{})
+	defer cleanup()
+
+	productSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		if req.ProductId == 2 && req.QuantityChange < 0 {
+			return nil, status.Error(codes.FailedPrecondition, "stock changed concurrently")
+		}
+		return nil, nil
+	}
+
+	_, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+		{ProductID: 1, Quantity: 4},
+		{ProductID: 2, Quantity: 1},
+	})
+
+	var orderErr *OrderError
+	require.True(t, errors.As(err, &orderErr))
+	assert.True(t, orderErr.Compensated)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.Equal(t, int32(10), productSrv.stock(1))
+	assert.Equal(t, int32(5), productSrv.stock(2))
+}
+
+// lostAnswers makes the first call for every idempotency key fail with
+// DeadlineExceeded, after applying it when applied is set.
+func lostAnswers(productSrv *mockProductServer, productID int64, applied bool) {
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	productSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := md.Get(interceptors.IdempotencyKeyMetadata)[0]
+
+		mu.Lock()
+		first := !seen[key]
+		seen[key] = true
+		mu.Unlock()
+
+		if req.ProductId != productID || req.QuantityChange > 0 || !first {
+			return nil, nil
+		}
+		if applied {
+			productSrv.applyStockUpdate(ctx, req)
+		}
+		return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+}
+
+func TestProcessor_CreateOrder_AmbiguousReservationIsSettled(t *testing.T) {
+	for _, applied := range []bool{true, false} {
+		t.Run(fmt.Sprintf("applied=%t", applied), func(t *testing.T) {
+			productSrv := newMockProductServer(testProducts()...)
+			orderSrv := &mockOrderServer{
+				CreateOrderFunc: func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+					return &order1.CreateOrderResponse{OrderId: 42}, nil
+				},
+			}
+			p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+			defer cleanup()
+
+			lostAnswers(productSrv, 2, applied)
+
+			order, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+				{ProductID: 1, Quantity: 4},
+				{ProductID: 2, Quantity: 1},
+			})
+			require.NoError(t, err, "re-sending the reservation under its key tells the outcome")
+			assert.Equal(t, int64(42), order.ID)
+			assert.Equal(t, int32(6), productSrv.stock(1))
+			assert.Equal(t, int32(4), productSrv.stock(2), "the reservation is applied exactly once")
+		})
+	}
+}
+
+func TestProcessor_CreateOrder_UnsettledReservationIsKept(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	p, cleanup := setupTestProcessor(t, productSrv, &mockOrderServer{})
+	defer cleanup()
+
+	productSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		if req.ProductId == 2 && req.QuantityChange < 0 {
+			return nil, status.Error(codes.DeadlineExceeded, "deadline exceeded")
+		}
+		return nil, nil
+	}
+
+	_, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+		{ProductID: 1, Quantity: 4},
+		{ProductID: 2, Quantity: 1},
+	})
+
+	var orderErr *OrderError
+	require.True(t, errors.As(err, &orderErr))
+	assert.False(t, orderErr.Compensated)
+	assert.Equal(t, []int64{2}, orderErr.UnsettledIDs)
+	assert.Equal(t, int32(10), productSrv.stock(1), "confirmed reservations are released")
+	assert.Equal(t, int32(5), productSrv.stock(2), "stock is not released for a reservation that never happened")
+}
+
+func TestProcessor_CreateOrder_AmbiguousOrder(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	// The order service commits the order but the answer is lost; a repeat
+	// of the key returns the stored order.
+	var mu sync.Mutex
+	orders := make(map[string]int64)
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		key := md.Get(interceptors.IdempotencyKeyMetadata)[0]
+
+		mu.Lock()
+		defer mu.Unlock()
+		if id, ok := orders[key]; ok {
+			return &order1.CreateOrderResponse{OrderId: id}, nil
+		}
+		orders[key] = int64(len(orders) + 1)
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+
+	order, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{{ProductID: 1, Quantity: 3}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), order.ID)
+	assert.Len(t, orders, 1, "a single order is created")
+	assert.Equal(t, int32(7), productSrv.stock(1), "its stock stays reserved")
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		return nil, status.Error(codes.Unavailable, "connection reset")
+	}
+
+	ctx := idempotency.WithKey(context.Background(), "user:7:k1")
+	_, err = p.CreateOrder(ctx, 7, []OrderItemInput{{ProductID: 1, Quantity: 2}})
+	assert.ErrorIs(t, err, ErrOrderOutcomeUnknown)
+	assert.True(t, idempotency.Kept(ctx), "the client's key is kept so a retry cannot reserve again")
+	var orderErr *OrderError
+	require.True(t, errors.As(err, &orderErr))
+	assert.False(t, orderErr.Compensated)
+	assert.Equal(t, []int64{1}, orderErr.UnsettledIDs)
+	assert.Equal(t, int32(5), productSrv.stock(1), "stock of an order that may exist is not released")
+}
+
+func TestProcessor_CreateOrder_IdempotencyKeys(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	var mu sync.Mutex
+	var stockKeys []string
+	productSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		mu.Lock()
+		stockKeys = append(stockKeys, md.Get(interceptors.IdempotencyKeyMetadata)...)
+		mu.Unlock()
+		return nil, nil
+	}
+	var orderKeys []string
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		orderKeys = append(orderKeys, md.Get(interceptors.IdempotencyKeyMetadata)...)
+		return &order1.CreateOrderResponse{OrderId: 42}, nil
+	}
+
+	items := []OrderItemInput{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 1}}
+	_, err := p.CreateOrder(idempotency.WithKey(context.Background(), "user:7:k1"), 7, items)
+	require.NoError(t, err)
+	_, err = p.CreateOrder(context.Background(), 7, items)
+	require.NoError(t, err)
+
+	require.Len(t, stockKeys, 4)
+	assert.Len(t, uniqueStrings(stockKeys), 4, "every reservation has its own key")
+	require.Len(t, orderKeys, 2)
+	assert.Equal(t, "user:7:k1/order", orderKeys[0], "the order reuses the client's key")
+	assert.NotEmpty(t, orderKeys[1], "an order without a client key still gets one")
+}
+
+func uniqueStrings(values []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+func TestProcessor_CreateOrder_CompensationFailureIsReported(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	orderSrv.CreateOrderFunc = func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+		return nil, status.Error(codes.FailedPrecondition, "user account is blocked")
+	}
+	productSrv.UpdateStockFunc = func(ctx context.Context, req *product1.UpdateStockRequest) (*emptypb.Empty, error) {
+		if req.ProductId == 1 && req.QuantityChange > 0 {
+			return nil, status.Error(codes.Internal, "stock database is down")
+		}
+		return nil, nil
+	}
+
+	_, err := p.CreateOrder(context.Background(), 7, []OrderItemInput{
+		{ProductID: 1, Quantity: 1},
+		{ProductID: 2, Quantity: 1},
+	})
+
+	var orderErr *OrderError
+	require.True(t, errors.As(err, &orderErr))
+	assert.False(t, orderErr.Compensated)
+	assert.Equal(t, []int64{1}, orderErr.FailedReleaseIDs)
+	assert.Contains(t, err.Error(), "failed to release reserved stock")
+	assert.Equal(t, int32(9), productSrv.stock(1))
+	assert.Equal(t, int32(5), productSrv.stock(2))
+}
+
+func TestProcessor_CreateOrder_InvalidItems(t *testing.T) {
+	p, cleanup := setupTestProcessor(t, newMockProductServer(), &mockOrderServer{})
+	defer cleanup()
+
+	_, err := p.CreateOrder(context.Background(), 7, nil)
+	assert.ErrorIs(t, err, ErrEmptyOrder)
+
+	_, err = p.CreateOrder(context.Background(), 7, []OrderItemInput{{ProductID: 1, Quantity: 0}})
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	_, err = p.CreateOrder(context.Background(), 7, []OrderItemInput{{ProductID: 0, Quantity: 1}})
+	assert.ErrorIs(t, err, ErrInvalidProductID)
+}
+
+func TestProcessor_GetOrder_Ownership(t *testing.T) {
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, newMockProductServer(), orderSrv)
+	defer cleanup()
+
+	orderSrv.GetOrderFunc = func(ctx context.Context, req *order1.GetOrderRequest) (*order1.GetOrderResponse, error) {
+		return &order1.GetOrderResponse{OrderDetails: &order1.OrderDetails{
+			OrderId:    req.OrderId,
+			UserId:     7,
+			Items:      []*order1.OrderItem{{ProductId: 1, Quantity: 2, Price: 100}},
+			TotalPrice: 200,
+			Status:     "created",
+		}}, nil
+	}
+
+	order, err := p.GetOrder(context.Background(), 7, 15)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), order.ID)
+	assert.Equal(t, "created", order.Status)
+	require.Len(t, order.Items, 1)
+	assert.Equal(t, int64(100), order.Items[0].Price)
+
+	_, err = p.GetOrder(context.Background(), 8, 15)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestProcessor_GetOrderView(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	orderSrv.GetOrderFunc = func(ctx context.Context, req *order1.GetOrderRequest) (*order1.GetOrderResponse, error) {
+		return &order1.GetOrderResponse{OrderDetails: &order1.OrderDetails{
+			OrderId: req.OrderId,
+			UserId:  7,
+			Items: []*order1.OrderItem{
+				{ProductId: 1, Quantity: 1, Price: 110000},
+				{ProductId: 2, Quantity: 1, Price: 2500},
+				{ProductId: 1, Quantity: 1, Price: 110000},
+				{ProductId: 3, Quantity: 1, Price: 900},
+			},
+		}}, nil
+	}
+
+	view, err := p.GetOrderView(context.Background(), 7, 15)
+	require.NoError(t, err)
+
+	require.Len(t, view.Items, 4)
+	assert.Equal(t, "Laptop", view.Items[0].Name)
+	assert.Equal(t, int64(110000), view.Items[0].Price, "stored order price must be kept")
+	assert.Equal(t, "Mouse", view.Items[1].Name)
+	assert.Empty(t, view.Items[3].Name)
+	assert.True(t, view.Partial)
+	assert.Equal(t, []int64{3}, view.MissingProductIDs)
+	assert.Equal(t, 1, productSrv.getProductCalls[1], "product ids must be deduplicated")
+
+	_, err = p.GetOrderView(context.Background(), 8, 15)
+	assert.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestProcessor_GetOrderView_SlowProductService(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	productSrv.GetProductDelay = 3 * time.Second
+	orderSrv := &mockOrderServer{}
+	p, cleanup := setupTestProcessor(t, productSrv, orderSrv)
+	defer cleanup()
+
+	orderSrv.GetOrderFunc = func(ctx context.Context, req *order1.GetOrderRequest) (*order1.GetOrderResponse, error) {
+		return &order1.GetOrderResponse{OrderDetails: &order1.OrderDetails{
+			OrderId: req.OrderId,
+			UserId:  7,
+			Items:   []*order1.OrderItem{{ProductId: 1, Quantity: 1, Price: 120000}},
+		}}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	view, err := p.GetOrderView(ctx, 7, 15)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.True(t, view.Partial)
+	assert.Equal(t, int64(120000), view.Items[0].Price)
+}
+
+func TestProcessor_LogsRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	s := &processorService{logger: slog.New(slog.NewJSONHandler(&logs, nil))}
+
+	ctx := requestid.NewContext(context.Background(), "req-123")
+	s.cartError(ctx, errors.New("store down"))
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+	assert.Equal(t, "req-123", entry["request_id"])
+	assert.Equal(t, "store down", entry["error"])
+}
@@ -0,0 +1,39 @@
+package processor
+
+import (
+	"context"
+
+	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
+)
+
+// getProduct reads a product through the cache when it is enabled.
+func (s *processorService) getProduct(ctx context.Context, id int64) (*product1.ProductDetails, error) {
+	if s.products == nil {
+		return s.productClient.GetProduct(ctx, id)
+	}
+	return s.products.Get(ctx, id, func(ctx context.Context) (*product1.ProductDetails, error) {
+		return s.productClient.GetProduct(ctx, id)
+	})
+}
+
+// listProducts reads a product listing through the cache when it is enabled.
+func (s *processorService) listProducts(ctx context.Context, filter string) ([]*product1.ProductDetails, error) {
+	if s.productLists == nil {
+		return s.productClient.ListProducts(ctx, filter)
+	}
+	return s.productLists.Get(ctx, filter, func(ctx context.Context) ([]*product1.ProductDetails, error) {
+		return s.productClient.ListProducts(ctx, filter)
+	})
+}
+
+// invalidateProduct drops what the cache knows about product id after its
+// stock was changed through the gateway. Every listing may include the
+// product, so all of them go too. It is called whether or not UpdateStock
+// succeeded, since a failed call may still have been applied.
+func (s *processorService) invalidateProduct(id int64) {
+	if s.products == nil {
+		return
+	}
+	s.products.Delete(id)
+	s.productLists.Purge()
+}
@@ -0,0 +1,81 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	order1 "github.com/KuranovNikita/ecomProto/gen/go/order"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCacheOptions = Options{ProductCache: CacheOptions{Size: 100, TTL: time.Minute}}
+
+func (s *mockProductServer) getProductCallCount(id int64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getProductCalls[id]
+}
+
+func TestProcessor_GetProduct_Cached(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	p, cleanup := setupTestProcessorWithOptions(t, productSrv, &mockOrderServer{}, testCacheOptions)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		product, err := p.GetProduct(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, "Laptop", product.Name)
+	}
+	assert.Equal(t, 1, productSrv.getProductCallCount(1))
+
+	_, err := p.GetProduct(context.Background(), 404)
+	require.Error(t, err)
+	_, err = p.GetProduct(context.Background(), 404)
+	require.Error(t, err)
+	assert.Equal(t, 2, productSrv.getProductCallCount(404), "errors are not cached")
+}
+
+func TestProcessor_GetProduct_CoalescesConcurrentMisses(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	productSrv.GetProductDelay = 50 * time.Millisecond
+	p, cleanup := setupTestProcessorWithOptions(t, productSrv, &mockOrderServer{}, testCacheOptions)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.GetProduct(context.Background(), 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, productSrv.getProductCallCount(1))
+}
+
+func TestProcessor_CreateOrder_InvalidatesCachedProducts(t *testing.T) {
+	productSrv := newMockProductServer(testProducts()...)
+	orderSrv := &mockOrderServer{
+		CreateOrderFunc: func(ctx context.Context, req *order1.CreateOrderRequest) (*order1.CreateOrderResponse, error) {
+			return &order1.CreateOrderResponse{OrderId: 42}, nil
+		},
+	}
+	p, cleanup := setupTestProcessorWithOptions(t, productSrv, orderSrv, testCacheOptions)
+	defer cleanup()
+
+	product, err := p.GetProduct(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, int32(10), product.StockCount)
+
+	_, err = p.CreateOrder(context.Background(), 7, []OrderItemInput{{ProductID: 1, Quantity: 2}})
+	require.NoError(t, err)
+
+	product, err = p.GetProduct(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, int32(8), product.StockCount, "the stock change is visible at once")
+}
@@ -0,0 +1,197 @@
+package processor
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	DefaultProductPageSize = 20
+	MaxProductPageSize     = 100
+)
+
+var ErrInvalidProductQuery = errors.New("invalid product query")
+
+// ProductQuery selects a page of products. Sort is "id" (the default),
+// "price", "name" or "stock", optionally prefixed with "-" for descending
+// order. A nil price bound is not applied. Cursor is taken from a previous
+// ProductPage and must be sent with the same Sort.
+type ProductQuery struct {
+	Filter   string
+	Sort     string
+	MinPrice *int64
+	MaxPrice *int64
+	InStock  bool
+	Limit    int
+	Cursor   string
+}
+
+// ProductPage holds one page of products and the cursors of the pages
+// around it; a cursor is empty when there is no such page.
+type ProductPage struct {
+	Products   []Product
+	NextCursor string
+	PrevCursor string
+}
+
+// productCursor is the position of a product in a sorted listing. It stores
+// the sort key of the product rather than an offset, so pages stay stable
+// when products are added or removed in between.
+type productCursor struct {
+	Sort   string `json:"s"`
+	ID     int64  `json:"i"`
+	Number int64  `json:"v,omitempty"`
+	Name   string `json:"n,omitempty"`
+	Before bool   `json:"b,omitempty"`
+}
+
+type productOrder struct {
+	field string
+	desc  bool
+}
+
+// ListProductsPage pages through ListProducts until the product service can
+// sort and paginate on its own. Filtering and sorting are applied to the
+// whole listing, which comes from the product cache when it is enabled.
+func (s *processorService) ListProductsPage(ctx context.Context, query ProductQuery) (*ProductPage, error) {
+	order, err := parseProductSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultProductPageSize
+	}
+	limit = min(limit, MaxProductPageSize)
+
+	var cursor *productCursor
+	if query.Cursor != "" {
+		cursor, err = decodeProductCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	products, err := s.ListProducts(ctx, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	products = slices.DeleteFunc(products, func(p Product) bool {
+		return (query.MinPrice != nil && p.Price < *query.MinPrice) ||
+			(query.MaxPrice != nil && p.Price > *query.MaxPrice) ||
+			(query.InStock && p.StockCount <= 0)
+	})
+	slices.SortFunc(products, order.compare)
+
+	start, end := 0, min(limit, len(products))
+	if cursor != nil {
+		key := cursor.product(order)
+		i, _ := slices.BinarySearchFunc(products, key, order.compare)
+		if cursor.Before {
+			start, end = max(0, i-limit), i
+		} else {
+			if i < len(products) && order.compare(products[i], key) == 0 {
+				i++
+			}
+			start, end = i, min(i+limit, len(products))
+		}
+	}
+
+	page := &ProductPage{Products: products[start:end]}
+	if end < len(products) && end > start {
+		page.NextCursor = encodeProductCursor(query.Sort, order, products[end-1], false)
+	}
+	if start > 0 && end > start {
+		page.PrevCursor = encodeProductCursor(query.Sort, order, products[start], true)
+	}
+	return page, nil
+}
+
+func parseProductSort(sort string) (productOrder, error) {
+	order := productOrder{field: strings.TrimPrefix(sort, "-"), desc: strings.HasPrefix(sort, "-")}
+	switch order.field {
+	case "":
+		if order.desc {
+			return productOrder{}, fmt.Errorf("%w: empty sort field", ErrInvalidProductQuery)
+		}
+		order.field = "id"
+	case "id", "price", "name", "stock":
+	default:
+		return productOrder{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidProductQuery, order.field)
+	}
+	return order, nil
+}
+
+// compare orders products by the sort field and then by id, so that the
+// order is total and a cursor identifies a single position.
+func (o productOrder) compare(a, b Product) int {
+	var c int
+	switch o.field {
+	case "price":
+		c = cmp.Compare(a.Price, b.Price)
+	case "name":
+		c = strings.Compare(a.Name, b.Name)
+	case "stock":
+		c = cmp.Compare(a.StockCount, b.StockCount)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.Id, b.Id)
+	}
+	if o.desc {
+		return -c
+	}
+	return c
+}
+
+func encodeProductCursor(sort string, order productOrder, p Product, before bool) string {
+	cursor := productCursor{Sort: sort, ID: p.Id, Before: before}
+	switch order.field {
+	case "price":
+		cursor.Number = p.Price
+	case "name":
+		cursor.Name = p.Name
+	case "stock":
+		cursor.Number = int64(p.StockCount)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(encoded, sort string) (*productCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidProductQuery)
+	}
+
+	var cursor productCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidProductQuery)
+	}
+	if cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort", ErrInvalidProductQuery)
+	}
+	return &cursor, nil
+}
+
+// product rebuilds the sort key the cursor points at.
+func (c *productCursor) product(order productOrder) Product {
+	p := Product{Id: c.ID}
+	switch order.field {
+	case "price":
+		p.Price = c.Number
+	case "name":
+		p.Name = c.Name
+	case "stock":
+		p.StockCount = int32(c.Number)
+	}
+	return p
+}
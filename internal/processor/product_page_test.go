@@ -0,0 +1,147 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	product1 "github.com/KuranovNikita/ecomProto/gen/go/product"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCatalogServer() *mockProductServer {
+	return newMockProductServer(
+		&product1.ProductDetails{Id: 1, Name: "Laptop", Price: 120000, StockCount: 10},
+		&product1.ProductDetails{Id: 2, Name: "Mouse", Price: 2500, StockCount: 0},
+		&product1.ProductDetails{Id: 3, Name: "Keyboard", Price: 7000, StockCount: 4},
+		&product1.ProductDetails{Id: 4, Name: "Monitor", Price: 30000, StockCount: 2},
+		&product1.ProductDetails{Id: 5, Name: "Cable", Price: 2500, StockCount: 50},
+	)
+}
+
+func productIDs(products []Product) []int64 {
+	ids := make([]int64, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.Id)
+	}
+	return ids
+}
+
+func TestProcessor_ListProductsPage_DefaultsToIDOrder(t *testing.T) {
+	proc, cleanup := setupTestProcessor(t, newCatalogServer(), &mockOrderServer{})
+	defer cleanup()
+
+	page, err := proc.ListProductsPage(context.Background(), ProductQuery{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5}, productIDs(page.Products))
+	assert.Empty(t, page.NextCursor)
+	assert.Empty(t, page.PrevCursor)
+}
+
+func TestProcessor_ListProductsPage_CursorsWalkBothWays(t *testing.T) {
+	proc, cleanup := setupTestProcessor(t, newCatalogServer(), &mockOrderServer{})
+	defer cleanup()
+	ctx := context.Background()
+
+	first, err := proc.ListProductsPage(ctx, ProductQuery{Sort: "price", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 5}, productIDs(first.Products), "equal prices are ordered by id")
+	assert.Empty(t, first.PrevCursor)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := proc.ListProductsPage(ctx, ProductQuery{Sort: "price", Limit: 2, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4}, productIDs(second.Products))
+	require.NotEmpty(t, second.NextCursor)
+	require.NotEmpty(t, second.PrevCursor)
+
+	last, err := proc.ListProductsPage(ctx, ProductQuery{Sort: "price", Limit: 2, Cursor: second.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, productIDs(last.Products))
+	assert.Empty(t, last.NextCursor)
+
+	back, err := proc.ListProductsPage(ctx, ProductQuery{Sort: "price", Limit: 2, Cursor: second.PrevCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 5}, productIDs(back.Products))
+	assert.Empty(t, back.PrevCursor)
+	assert.Equal(t, first.NextCursor, back.NextCursor)
+}
+
+func TestProcessor_ListProductsPage_CursorSurvivesCatalogChanges(t *testing.T) {
+	srv := newCatalogServer()
+	proc, cleanup := setupTestProcessor(t, srv, &mockOrderServer{})
+	defer cleanup()
+	ctx := context.Background()
+
+	first, err := proc.ListProductsPage(ctx, ProductQuery{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, productIDs(first.Products))
+
+	srv.mu.Lock()
+	delete(srv.products, 2)
+	srv.mu.Unlock()
+
+	next, err := proc.ListProductsPage(ctx, ProductQuery{Limit: 2, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4}, productIDs(next.Products), "removing a seen product does not skip one")
+}
+
+func TestProcessor_ListProductsPage_Sorts(t *testing.T) {
+	proc, cleanup := setupTestProcessor(t, newCatalogServer(), &mockOrderServer{})
+	defer cleanup()
+
+	tests := []struct {
+		sort string
+		want []int64
+	}{
+		{sort: "id", want: []int64{1, 2, 3, 4, 5}},
+		{sort: "-id", want: []int64{5, 4, 3, 2, 1}},
+		{sort: "-price", want: []int64{1, 4, 3, 5, 2}},
+		{sort: "name", want: []int64{5, 3, 1, 4, 2}},
+		{sort: "-stock", want: []int64{5, 1, 3, 4, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			page, err := proc.ListProductsPage(context.Background(), ProductQuery{Sort: tt.sort})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, productIDs(page.Products))
+		})
+	}
+}
+
+func TestProcessor_ListProductsPage_Filters(t *testing.T) {
+	proc, cleanup := setupTestProcessor(t, newCatalogServer(), &mockOrderServer{})
+	defer cleanup()
+
+	minPrice, maxPrice := int64(2500), int64(30000)
+	page, err := proc.ListProductsPage(context.Background(), ProductQuery{
+		MinPrice: &minPrice,
+		MaxPrice: &maxPrice,
+		InStock:  true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3, 4, 5}, productIDs(page.Products))
+}
+
+func TestProcessor_ListProductsPage_InvalidQuery(t *testing.T) {
+	proc, cleanup := setupTestProcessor(t, newCatalogServer(), &mockOrderServer{})
+	defer cleanup()
+	ctx := context.Background()
+
+	first, err := proc.ListProductsPage(ctx, ProductQuery{Sort: "price", Limit: 2})
+	require.NoError(t, err)
+
+	queries := map[string]ProductQuery{
+		"unknown sort":     {Sort: "rating"},
+		"bare minus":       {Sort: "-"},
+		"malformed cursor": {Cursor: "not a cursor"},
+		"sort mismatch":    {Sort: "name", Cursor: first.NextCursor},
+	}
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			_, err := proc.ListProductsPage(ctx, query)
+			assert.ErrorIs(t, err, ErrInvalidProductQuery)
+		})
+	}
+}
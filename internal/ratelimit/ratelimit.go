@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	ttlmap "ecomGateway/internal/lib/ttl_map"
+)
+
+// Limit configures a token bucket: Burst requests may be made at once and the
+// bucket refills at Rate tokens per second. A zero Rate disables the limit.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Store keeps token buckets. With MemoryStore every gateway instance counts
+// on its own, so N replicas let through N times the limit; a shared
+// implementation (e.g. Redis) makes them enforce one.
+type Store interface {
+	// Take removes one token from the bucket for key. When the bucket is
+	// empty it reports false and how long until a token is available.
+	Take(ctx context.Context, key string, limit Limit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Limiter applies one Limit to the keys it is given. The prefix keeps keys of
+// limiters that share a store apart.
+type Limiter struct {
+	store  Store
+	prefix string
+	limit  Limit
+}
+
+func NewLimiter(store Store, prefix string, limit Limit) *Limiter {
+	return &Limiter{store: store, prefix: prefix, limit: limit}
+}
+
+func (l *Limiter) Allow(ctx context.Context, key string) (bool, time.Duration, error) {
+	const op = "ratelimit.allow"
+
+	allowed, retryAfter, err := l.store.Take(ctx, l.prefix+":"+key, l.limit)
+	if err != nil {
+		return false, 0, fmt.Errorf("%s: %w", op, err)
+	}
+	return allowed, retryAfter, nil
+}
+
+// sweepInterval is how often MemoryStore drops buckets that have refilled
+// completely and so carry no state.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets *ttlmap.Map[string, *bucket]
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: ttlmap.New[string](sweepInterval, (*bucket).full),
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets.Get(key, now)
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		s.buckets.Set(key, b)
+	}
+	b.refill(now)
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// full reports whether b has refilled completely by now, at which point it is
+// no different from a new bucket and can be dropped.
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// IPResolver finds the client address of a request. X-Forwarded-For is only
+// honoured when the connection comes from a trusted proxy, and then only the
+// hops added by trusted proxies are skipped.
+type IPResolver struct {
+	trusted []netip.Prefix
+}
+
+// NewIPResolver parses the trusted proxies, given as CIDRs or single IPs.
+func NewIPResolver(trustedProxies []string) (*IPResolver, error) {
+	const op = "ratelimit.NewIPResolver"
+
+	r := &IPResolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(proxy)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%s: invalid trusted proxy %q: %w", op, proxy, err)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+func (r *IPResolver) ClientIP(req *http.Request) string {
+	remote := parseAddr(req.RemoteAddr)
+	if !remote.IsValid() {
+		return req.RemoteAddr
+	}
+	if !r.isTrusted(remote) {
+		return remote.String()
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := parseAddr(strings.TrimSpace(hops[i]))
+		if !hop.IsValid() {
+			break
+		}
+		remote = hop
+		if !r.isTrusted(hop) {
+			break
+		}
+	}
+	return remote.String()
+}
+
+func (r *IPResolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr accepts both "ip" and "ip:port" forms.
+func parseAddr(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
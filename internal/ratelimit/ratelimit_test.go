@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	return store, &now
+}
+
+func TestMemoryStore_TokenBucket(t *testing.T) {
+	store, now := newTestStore()
+	limiter := NewLimiter(store, "test", Limit{Rate: 2, Burst: 3})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := limiter.Allow(ctx, "a")
+		require.NoError(t, err)
+		assert.True(t, allowed, "request %d is within the burst", i+1)
+	}
+
+	allowed, retryAfter, err := limiter.Allow(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	allowed, _, err = limiter.Allow(ctx, "b")
+	require.NoError(t, err)
+	assert.True(t, allowed, "keys have separate buckets")
+
+	*now = now.Add(500 * time.Millisecond)
+	allowed, _, err = limiter.Allow(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, allowed, "one token refills after 1/rate")
+
+	allowed, _, err = limiter.Allow(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestMemoryStore_SweepsFullBuckets(t *testing.T) {
+	store, now := newTestStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	ctx := context.Background()
+
+	_, _, err := store.Take(ctx, "idle", limit)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.buckets.Len())
+
+	*now = now.Add(sweepInterval)
+	_, _, err = store.Take(ctx, "active", limit)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, store.buckets.Len(), "the idle bucket is swept")
+	_, ok := store.buckets.Get("active", *now)
+	assert.True(t, ok)
+}
+
+func TestIPResolver_ClientIP(t *testing.T) {
+	resolver, err := NewIPResolver([]string{"10.0.0.0/8", "192.168.1.1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       string
+	}{
+		{"direct client", "203.0.113.7:5000", "", "203.0.113.7"},
+		{"untrusted peer cannot spoof", "203.0.113.7:5000", "1.2.3.4", "203.0.113.7"},
+		{"trusted proxy", "10.0.0.1:5000", "198.51.100.2", "198.51.100.2"},
+		{"chain of trusted proxies", "10.0.0.1:5000", "1.2.3.4, 198.51.100.2, 192.168.1.1", "198.51.100.2"},
+		{"trusted proxy without header", "192.168.1.1:5000", "", "192.168.1.1"},
+		{"malformed hop", "10.0.0.1:5000", "garbage", "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			assert.Equal(t, tt.want, resolver.ClientIP(req))
+		})
+	}
+}
+
+func TestNewIPResolver_Invalid(t *testing.T) {
+	_, err := NewIPResolver([]string{"not-an-ip"})
+	require.Error(t, err)
+}
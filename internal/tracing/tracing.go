@@ -0,0 +1,228 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const instrumentationName = "ecomGateway/internal/tracing"
+
+// Supported span exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterMemory = "memory"
+	ExporterOTLP   = "otlp"
+)
+
+const unmatchedRoute = "unmatched"
+
+type Options struct {
+	ServiceName string
+	Exporter    string
+	// Endpoint is the host:port of the OTLP gRPC collector.
+	Endpoint string
+	Insecure bool
+	// SampleRatio is the fraction of new traces that are recorded. Incoming
+	// sampled parents are always honoured.
+	SampleRatio float64
+}
+
+// Provider owns the tracer provider installed as the global one.
+type Provider struct {
+	tp *sdktrace.TracerProvider
+	// Memory holds the finished spans when the memory exporter is used.
+	Memory *tracetest.InMemoryExporter
+}
+
+// Setup builds the exporter selected in opts and installs the tracer provider
+// and the W3C trace context propagator globally. With ExporterNone the global
+// no-op provider is kept and only the propagator is installed, so incoming
+// trace context still flows to the backends.
+func Setup(ctx context.Context, opts Options) (*Provider, error) {
+	const op = "tracing.setup"
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	p := &Provider{}
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return p, nil
+	case ExporterStdout:
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		exporter = exp
+	case ExporterMemory:
+		p.Memory = tracetest.NewInMemoryExporter()
+		exporter = p.Memory
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exp, err := otlptracegrpc.New(ctx, clientOpts...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		exporter = exp
+	default:
+		return nil, fmt.Errorf("%s: unknown exporter %q", op, opts.Exporter)
+	}
+
+	res := resource.NewSchemaless(semconv.ServiceName(opts.ServiceName))
+
+	spanProcessor := sdktrace.NewBatchSpanProcessor(exporter)
+	if opts.Exporter == ExporterMemory {
+		spanProcessor = sdktrace.NewSimpleSpanProcessor(exporter)
+	}
+
+	p.tp = sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(spanProcessor),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(p.tp)
+
+	return p, nil
+}
+
+// Shutdown flushes pending spans and stops the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	const op = "tracing.shutdown"
+
+	if p.tp == nil {
+		return nil
+	}
+	if err := p.tp.Shutdown(ctx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Middleware starts a server span for every request, continuing the trace from
+// an incoming traceparent header. Spans are named after the chi route pattern
+// so that paths with ids do not produce distinct span names.
+func Middleware(routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := unmatchedRoute
+			rctx := chi.NewRouteContext()
+			if routes.Match(rctx, r.Method, r.URL.Path) {
+				route = rctx.RoutePattern()
+			}
+
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer().Start(ctx, r.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(code))
+			if code >= http.StatusInternalServerError {
+				span.SetStatus(otelcodes.Error, http.StatusText(code))
+			}
+		})
+	}
+}
+
+// UnaryClientInterceptor starts a client span for a call to backend and
+// injects the trace context into the outgoing metadata. It must be the first
+// interceptor in the chain so that retries share the caller's span.
+func UnaryClientInterceptor(backend string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := tracer().Start(ctx, method,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.RPCSystemGRPC,
+				semconv.RPCMethod(method),
+				attribute.String("backend", backend),
+			),
+		)
+		defer span.End()
+
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+		ctx = metadata.NewOutgoingContext(ctx, md)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		code := status.Code(err)
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(otelcodes.Error, code.String())
+		}
+
+		return err
+	}
+}
+
+// metadataCarrier adapts outgoing gRPC metadata to a propagation carrier.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
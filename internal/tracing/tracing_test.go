@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	parentTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentSpanID  = "00f067aa0ba902b7"
+)
+
+func setupTestProvider(t *testing.T) *Provider {
+	t.Helper()
+
+	p, err := Setup(context.Background(), Options{
+		ServiceName: "test",
+		Exporter:    ExporterMemory,
+		SampleRatio: 1,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	return p
+}
+
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	p := setupTestProvider(t)
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return status.Error(codes.NotFound, "no such product")
+	}
+
+	router := chi.NewRouter()
+	router.Use(Middleware(router))
+	router.Get("/products/{id}", func(w http.ResponseWriter, r *http.Request) {
+		err := UnaryClientInterceptor("product")(r.Context(), "/product.ProductService/GetProduct", nil, nil, nil, invoker)
+		assert.Error(t, err)
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/products/42", nil)
+	req.Header.Set("traceparent", "00-"+parentTraceID+"-"+parentSpanID+"-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := p.Memory.GetSpans()
+	require.Len(t, spans, 2)
+
+	client, server := spans[0], spans[1]
+
+	assert.Equal(t, "GET /products/{id}", server.Name)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, parentTraceID, server.SpanContext.TraceID().String())
+	assert.Equal(t, parentSpanID, server.Parent.SpanID().String())
+	assert.Equal(t, otelcodes.Unset, server.Status.Code, "4xx responses are not server errors")
+
+	assert.Equal(t, "/product.ProductService/GetProduct", client.Name)
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+	assert.Equal(t, otelcodes.Error, client.Status.Code)
+
+	require.NotNil(t, outgoing)
+	assert.Equal(t,
+		[]string{"00-" + parentTraceID + "-" + client.SpanContext.SpanID().String() + "-01"},
+		outgoing.Get("traceparent"),
+	)
+}
+
+func TestMiddleware_ServerError(t *testing.T) {
+	p := setupTestProvider(t)
+
+	router := chi.NewRouter()
+	router.Use(Middleware(router))
+	router.Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/orders", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	spans := p.Memory.GetSpans()
+	require.Len(t, spans, 2)
+
+	assert.Equal(t, "POST /orders", spans[0].Name)
+	assert.Equal(t, otelcodes.Error, spans[0].Status.Code)
+	assert.False(t, spans[0].Parent.IsValid(), "a request without traceparent starts a new trace")
+
+	assert.Equal(t, "GET "+unmatchedRoute, spans[1].Name)
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: "zipkin"})
+	require.Error(t, err)
+}